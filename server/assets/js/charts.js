@@ -27,6 +27,51 @@
                 
                 // Store for resize
                 container._echartsInstance = chart;
+
+                // Points of the main timeline series open their assessment
+                if (container.dataset.points) {
+                    var points = JSON.parse(container.dataset.points);
+                    chart.on('click', function(params) {
+                        if (params.seriesIndex === 0 && points[params.dataIndex]) {
+                            htmx.ajax('GET', '/assessment/history/' + points[params.dataIndex], {
+                                target: 'main#content',
+                                swap: 'innerHTML'
+                            });
+                        }
+                    });
+                }
+
+                // Calendar days open their assessment
+                if (container.dataset.assessments) {
+                    var assessments = JSON.parse(container.dataset.assessments);
+                    chart.on('click', function(params) {
+                        var id = assessments[params.value[0]];
+                        if (id) {
+                            htmx.ajax('GET', '/assessment/history/' + id, {
+                                target: 'main#content',
+                                swap: 'innerHTML'
+                            });
+                        }
+                    });
+                }
+
+                // Matrix cells open the scatter plot of their two series
+                if (container.dataset.series) {
+                    var series = JSON.parse(container.dataset.series);
+                    chart.on('click', function(params) {
+                        var x = series[params.value[0]];
+                        var y = series[params.value[1]];
+                        htmx.ajax('GET', '/assessment/results?corr_x=' + encodeURIComponent(x) + '&corr_y=' + encodeURIComponent(y), {
+                            target: 'main#content',
+                            swap: 'innerHTML'
+                        }).then(function() {
+                            var scatter = document.getElementById('correlation-chart');
+                            if (scatter) {
+                                scatter.scrollIntoView({ behavior: 'smooth' });
+                            }
+                        });
+                    });
+                }
                 
             } catch (error) {
                 console.error('Error initializing chart:', container.id, error);
@@ -19,6 +19,8 @@ function initCPT(containerId, settings, onTestEnd) {
         targetProbability: 0.7,
         targets: ['X'],
         nonTargets: ['A', 'B', 'C', 'E', 'F', 'H', 'K', 'L'],
+        practiceTrials: 0,
+        practiceEnabled: false,
         ...settings,
     };
 
@@ -28,20 +30,26 @@ function initCPT(containerId, settings, onTestEnd) {
     const interStimulusInterval = parseInt(testSettings.interStimulusInterval, 10);
     const targetProbability = parseFloat(testSettings.targetProbability);
     const nonTargetArray = testSettings.nonTargets.split(',').map(s => s.trim());
+    const practiceTrials = parseInt(testSettings.practiceTrials, 10) || 0;
+    const practiceEnabled = (testSettings.practiceEnabled === true || testSettings.practiceEnabled === 'true') && practiceTrials > 0;
 
     // --- State Variables ---
     let isRunning = false;
+    let isPractice = false;
+    let practiceRemaining = 0;
     let remainingTime = testDuration;
     let timerIntervalRef, stimulusTimeoutRef;
     let currentStimulus = null;
     let stimulusStartTime = 0;
     const testData = {
+        practiceStartTime: 0,
         testStartTime: 0,
         testEndTime: 0,
         stimuliPresented: [],
         responses: [],
         settings: testSettings,
     };
+    const focusMonitor = createFocusMonitor(testData);
 
     function formatTime(ms) {
         const minutes = Math.floor(ms / 60000);
@@ -57,11 +65,43 @@ function initCPT(containerId, settings, onTestEnd) {
         `;
     }
 
+    function renderPractice() {
+        container.innerHTML = `
+            <div class="text-lg mb-4">Practice</div>
+            <div id="cpt-stimulus-display" class="w-full h-48 bg-gray-200 flex items-center justify-center text-6xl font-bold rounded-lg"></div>
+            <p id="cpt-feedback" class="mt-4 h-6 font-semibold"></p>
+            <p class="mt-4 text-secondary">Press the SPACEBAR for '${testSettings.targets[0]}' only. This round is not scored.</p>
+        `;
+    }
+
+    // Tells the user how they did on the practice stimulus that was just shown.
+    function showPracticeFeedback(stimulus) {
+        const feedbackEl = document.getElementById('cpt-feedback');
+        if (!feedbackEl) return;
+        let message, correct;
+        if (stimulus.isTarget) {
+            correct = stimulus.responded;
+            message = correct ? 'Correct!' : `Missed - press SPACE when you see '${testSettings.targets[0]}'.`;
+        } else {
+            correct = !stimulus.responded;
+            message = correct ? 'Correct!' : `Don't press for '${stimulus.value}'.`;
+        }
+        feedbackEl.textContent = message;
+        feedbackEl.className = `mt-4 h-6 font-semibold ${correct ? 'text-green-700' : 'text-red-700'}`;
+    }
+
     function presentStimulus() {
         if (!isRunning) return;
         const stimulusEl = document.getElementById('cpt-stimulus-display');
         if (!stimulusEl) return; // Stop if the element is gone
 
+        if (isPractice) {
+            if (practiceRemaining <= 0) return endPractice();
+            practiceRemaining--;
+            const feedbackEl = document.getElementById('cpt-feedback');
+            if (feedbackEl) feedbackEl.textContent = '';
+        }
+
         const isTarget = Math.random() < targetProbability;
         const stimulusValue = isTarget ? testSettings.targets[0] : nonTargetArray[Math.floor(Math.random() * nonTargetArray.length)];
 
@@ -69,15 +109,18 @@ function initCPT(containerId, settings, onTestEnd) {
         currentStimulus = { value: stimulusValue, isTarget, responded: false };
         stimulusEl.textContent = stimulusValue;
 
+        const startTime = isPractice ? testData.practiceStartTime : testData.testStartTime;
         testData.stimuliPresented.push({
             value: stimulusValue,
             isTarget: isTarget,
-            presentedAt: stimulusStartTime - testData.testStartTime,
+            presentedAt: stimulusStartTime - startTime,
+            isPractice: isPractice,
         });
 
         // Hide the stimulus after its duration
         setTimeout(() => {
             if (stimulusEl) stimulusEl.textContent = '';
+            if (isPractice && currentStimulus) showPracticeFeedback(currentStimulus);
             currentStimulus = null;
             // Schedule the next stimulus
             stimulusTimeoutRef = setTimeout(presentStimulus, interStimulusInterval);
@@ -92,13 +135,38 @@ function initCPT(containerId, settings, onTestEnd) {
             stimulus: currentStimulus.value,
             isTarget: currentStimulus.isTarget,
             responseTime: performance.now() - stimulusStartTime,
-            stimulusIndex: testData.stimuliPresented.length - 1
+            stimulusIndex: testData.stimuliPresented.length - 1,
+            isPractice: isPractice,
         });
     }
 
+    function startPractice() {
+        isRunning = true;
+        isPractice = true;
+        practiceRemaining = practiceTrials;
+        testData.practiceStartTime = performance.now();
+        renderPractice();
+        document.addEventListener('keydown', handleKeyPress);
+        setTimeout(presentStimulus, interStimulusInterval);
+    }
+
+    function endPractice() {
+        isRunning = false;
+        isPractice = false;
+        clearTimeout(stimulusTimeoutRef);
+        document.removeEventListener('keydown', handleKeyPress);
+
+        container.innerHTML = `
+            <p class="text-lg font-semibold mb-4">Practice complete. The real test starts when you are ready.</p>
+            <button id="start-cpt-btn" class="primary-button">Start Test</button>
+        `;
+        document.getElementById('start-cpt-btn').addEventListener('click', startTest);
+    }
+
     function startTest() {
         isRunning = true;
         testData.testStartTime = performance.now();
+        focusMonitor.start();
         renderActiveTest();
         document.addEventListener('keydown', handleKeyPress);
 
@@ -120,6 +188,7 @@ function initCPT(containerId, settings, onTestEnd) {
         clearInterval(timerIntervalRef);
         clearTimeout(stimulusTimeoutRef);
         document.removeEventListener('keydown', handleKeyPress);
+        focusMonitor.stop();
         
         testData.testEndTime = performance.now();
         container.innerHTML = `<p class="text-lg font-semibold">Test complete. Saving results...</p>`;
@@ -130,6 +199,11 @@ function initCPT(containerId, settings, onTestEnd) {
     }
 
     // Initial render of the start button
-    container.innerHTML = `<button id="start-cpt-btn" class="primary-button">Start Test</button>`;
-    document.getElementById('start-cpt-btn').addEventListener('click', startTest);
+    if (practiceEnabled) {
+        container.innerHTML = `<button id="start-cpt-practice-btn" class="primary-button">Start Practice</button>`;
+        document.getElementById('start-cpt-practice-btn').addEventListener('click', startPractice);
+    } else {
+        container.innerHTML = `<button id="start-cpt-btn" class="primary-button">Start Test</button>`;
+        document.getElementById('start-cpt-btn').addEventListener('click', startTest);
+    }
 }
\ No newline at end of file
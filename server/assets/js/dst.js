@@ -18,6 +18,8 @@ function initDST(containerId, settings, onTestEnd) {
         interDigitInterval: 500,
         recallTimeout: 10000,
         trialsPerSpan: 2,
+        practiceTrials: 0,
+        practiceEnabled: false,
         ...settings,
     };
 
@@ -28,9 +30,14 @@ function initDST(containerId, settings, onTestEnd) {
     const interDigitInterval = parseInt(testSettings.interDigitInterval, 10);
     const recallTimeout = parseInt(testSettings.recallTimeout, 10);
     const trialsPerSpan = parseInt(testSettings.trialsPerSpan, 10);
+    const practiceTrials = parseInt(testSettings.practiceTrials, 10) || 0;
+    const practiceEnabled = (testSettings.practiceEnabled === true || testSettings.practiceEnabled === 'true') && practiceTrials > 0;
+    const practiceSpan = Math.max(2, initialSpan - 1); // Practice sequences are kept short
     
     // --- State Variables ---
     let phase = 'idle'; // idle, presenting, recalling
+    let isPractice = false;
+    let practiceTrial = 0;
     let currentSpan = initialSpan;
     let trial = 1;
     let currentSequence = [];
@@ -43,6 +50,7 @@ function initDST(containerId, settings, onTestEnd) {
         results: [],
         settings: testSettings,
     };
+    const focusMonitor = createFocusMonitor(testData);
 
     function generateSequence(length) {
         return Array.from({ length }, () => Math.floor(Math.random() * 9) + 1);
@@ -60,7 +68,7 @@ function initDST(containerId, settings, onTestEnd) {
         if (phase !== 'presenting') return;
         
         container.innerHTML = `
-            <div class="text-lg mb-4">Span: ${currentSpan}, Trial: ${trial}</div>
+            <div class="text-lg mb-4">${isPractice ? `Practice ${practiceTrial} of ${practiceTrials}` : `Span: ${currentSpan}, Trial: ${trial}`}</div>
             <div id="dst-stimulus-display" class="w-full h-48 bg-gray-200 flex items-center justify-center text-6xl font-bold rounded-lg">
                 ${currentSequence[displayIndex]}
             </div>
@@ -123,7 +131,17 @@ function initDST(containerId, settings, onTestEnd) {
         recallTimer = null; 
 
         const correct = userInput === currentSequence.join('');
-        testData.results.push({ span: currentSpan, trial, sequence: currentSequence.join(''), input: userInput, correct, timestamp: performance.now() - testData.testStartTime });
+        if (isPractice) {
+            testData.results.push({ span: practiceSpan, trial: practiceTrial, sequence: currentSequence.join(''), input: userInput, correct, timestamp: performance.now() - testData.testStartTime, isPractice: true });
+            container.innerHTML = `
+                <p class="text-2xl font-bold ${correct ? 'text-green-700' : 'text-red-700'}">${correct ? 'Correct!' : 'Incorrect'}</p>
+                ${correct ? '' : `<p class="mt-2">The sequence was ${currentSequence.join(' ')}.</p>`}
+            `;
+            setTimeout(nextPracticeTrial, 2500);
+            return;
+        }
+
+        testData.results.push({ span: currentSpan, trial, sequence: currentSequence.join(''), input: userInput, correct, timestamp: performance.now() - testData.testStartTime, isPractice: false });
 
         container.innerHTML = `<p class="text-2xl font-bold ${correct ? 'text-green-700' : 'text-red-700'}">${correct ? 'Correct!' : 'Incorrect'}</p>`;
         
@@ -141,13 +159,42 @@ function initDST(containerId, settings, onTestEnd) {
         }, 1500);
     }
     
+    function nextPracticeTrial() {
+        practiceTrial++;
+        if (practiceTrial > practiceTrials) return endPractice();
+        isPractice = true;
+        startTrial(practiceSpan);
+    }
+
+    function endPractice() {
+        isPractice = false;
+        phase = 'idle';
+        container.innerHTML = `
+            <p class="text-lg font-semibold mb-4">Practice complete. The real test starts when you are ready.</p>
+            <button id="start-dst-btn" class="primary-button">Start Test</button>
+        `;
+        document.getElementById('start-dst-btn').onclick = startScoredTrials;
+    }
+
+    // Focus is only monitored while scored trials are running.
+    function startScoredTrials() {
+        focusMonitor.start();
+        startTrial(currentSpan);
+    }
+
     function endTest() {
         phase = 'complete';
+        focusMonitor.stop();
         testData.testEndTime = performance.now();
         container.innerHTML = `<p class="text-lg font-semibold">Test complete. Saving results...</p>`;
         if (onTestEnd) onTestEnd(testData);
     }
 
-    container.innerHTML = `<button id="start-dst-btn" class="primary-button">Start Test</button>`;
-    document.getElementById('start-dst-btn').onclick = () => startTrial(currentSpan);
+    if (practiceEnabled) {
+        container.innerHTML = `<button id="start-dst-practice-btn" class="primary-button">Start Practice</button>`;
+        document.getElementById('start-dst-practice-btn').onclick = nextPracticeTrial;
+    } else {
+        container.innerHTML = `<button id="start-dst-btn" class="primary-button">Start Test</button>`;
+        document.getElementById('start-dst-btn').onclick = startScoredTrials;
+    }
 }
\ No newline at end of file
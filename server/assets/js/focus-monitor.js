@@ -0,0 +1,39 @@
+/**
+ * Tracks focus loss while a cognitive test is running. The counts are written
+ * onto the test's data object so the server can flag the run as invalid.
+ * @param {object} testData - The data object the test sends to the server.
+ * @returns {{start: function, stop: function}}
+ */
+function createFocusMonitor(testData) {
+    testData.focusLostCount = 0;
+    testData.timeHidden = 0;
+    let hiddenAt = null;
+
+    function handleBlur() {
+        testData.focusLostCount++;
+    }
+
+    function handleVisibilityChange() {
+        if (document.hidden) {
+            hiddenAt = performance.now();
+        } else if (hiddenAt !== null) {
+            testData.timeHidden += performance.now() - hiddenAt;
+            hiddenAt = null;
+        }
+    }
+
+    return {
+        start() {
+            window.addEventListener('blur', handleBlur);
+            document.addEventListener('visibilitychange', handleVisibilityChange);
+        },
+        stop() {
+            if (hiddenAt !== null) {
+                testData.timeHidden += performance.now() - hiddenAt;
+                hiddenAt = null;
+            }
+            window.removeEventListener('blur', handleBlur);
+            document.removeEventListener('visibilitychange', handleVisibilityChange);
+        },
+    };
+}
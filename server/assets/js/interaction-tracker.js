@@ -7,6 +7,14 @@ class InteractionTracker {
         this.currentQuestion = null;
         this.currentTarget = null;
         this.startTime = performance.now();
+
+        // Chunked upload state. Every chunk carries this session's ID and a sequence
+        // number so the server can drop retransmissions. Chunks stay pending until
+        // acknowledged and survive a reload via sessionStorage.
+        this.sessionId = crypto.randomUUID();
+        this.sessionStart = performance.timeOrigin + this.startTime;
+        this.nextSeq = 0;
+        this.pendingChunks = this.loadPendingChunks();
         
         // Throttling for performance
         this.lastRecordedTime = 0;
@@ -15,19 +23,63 @@ class InteractionTracker {
         this.sendInterval = 30000; // Send data every 30 seconds
         this.intervalId = null;
 
+        // Touch gestures in progress, keyed by pointer ID, and the most recent
+        // pointer press so clicks can be attributed to the device that made them.
+        this.touchGestures = [];
+        this.pageEvents = [];
+        this.activeTouches = new Map();
+        this.lastPointerDown = null;
+
+        // Keystrokes are reduced to categories unless the server asks for raw keys.
+        const captureMeta = document.querySelector('meta[name="keystroke-capture"]');
+        this.keystrokeCapture = captureMeta ? captureMeta.getAttribute('content') : 'categories';
+        this.activePresses = new Map();
+        this.nextPressId = 0;
+
         // Store event listener references for proper cleanup
         this.mouseMoveListener = this.handleMouseMove.bind(this);
+        this.pointerDownListener = this.handlePointerDown.bind(this);
+        this.pointerUpListener = this.handlePointerUp.bind(this);
         this.keyDownListener = this.handleKeyDown.bind(this);
         this.keyUpListener = this.handleKeyUp.bind(this);
+        this.changeListener = this.handleChange.bind(this);
+        this.visibilityListener = () => this.recordPageState(document.hidden ? 'hidden' : 'visible');
+        this.blurListener = () => this.recordPageState('blur');
+        this.focusListener = () => this.recordPageState('focus');
+        this.resizeListener = this.handleResize.bind(this);
+        this.scrollListener = this.handleScroll.bind(this);
+
+        // Resize and scroll fire continuously while they happen, so they are
+        // recorded at most this often.
+        this.pageEventInterval = 200;
+        this.lastResizeTime = 0;
+        this.lastScrollTime = 0;
+
+        // Questions currently on screen, so each gets a question_left event when the
+        // user submits or navigates away.
+        this.shownQuestions = new WeakSet();
+        this.openQuestions = new Set();
         
         // Initialize tracking
         this.setupListeners();
     }
     
     setupListeners() {
-        document.addEventListener('mousemove', this.mouseMoveListener);
+        document.addEventListener('pointermove', this.mouseMoveListener);
+        document.addEventListener('pointerdown', this.pointerDownListener);
+        document.addEventListener('pointerup', this.pointerUpListener);
+        document.addEventListener('pointercancel', this.pointerUpListener);
         document.addEventListener('keydown', this.keyDownListener);
         document.addEventListener('keyup', this.keyUpListener);
+        document.addEventListener('change', this.changeListener);
+        document.addEventListener('visibilitychange', this.visibilityListener);
+        window.addEventListener('blur', this.blurListener);
+        window.addEventListener('focus', this.focusListener);
+        window.addEventListener('resize', this.resizeListener);
+        window.addEventListener('scroll', this.scrollListener, { passive: true });
+
+        // Leaving a question (Next or Previous) ends its view.
+        document.body.addEventListener('htmx:beforeRequest', () => this.leaveQuestions());
         
         // Listen for HTMX navigation to send data before swapping content ---
         document.body.addEventListener('htmx:beforeSwap', () => {
@@ -50,9 +102,18 @@ class InteractionTracker {
     }
     
     cleanup() {
-        document.removeEventListener('mousemove', this.mouseMoveListener);
+        document.removeEventListener('pointermove', this.mouseMoveListener);
+        document.removeEventListener('pointerdown', this.pointerDownListener);
+        document.removeEventListener('pointerup', this.pointerUpListener);
+        document.removeEventListener('pointercancel', this.pointerUpListener);
         document.removeEventListener('keydown', this.keyDownListener);
         document.removeEventListener('keyup', this.keyUpListener);
+        document.removeEventListener('change', this.changeListener);
+        document.removeEventListener('visibilitychange', this.visibilityListener);
+        window.removeEventListener('blur', this.blurListener);
+        window.removeEventListener('focus', this.focusListener);
+        window.removeEventListener('resize', this.resizeListener);
+        window.removeEventListener('scroll', this.scrollListener);
         
         if (this.mutationObserver) {
             this.mutationObserver.disconnect();
@@ -85,6 +146,17 @@ class InteractionTracker {
                 }
             }
             
+            if (questionId && !this.shownQuestions.has(section)) {
+                this.shownQuestions.add(section);
+                this.openQuestions.add(questionId);
+                const timestamp = performance.now() - this.startTime;
+                this.pageEvents.push({ type: 'question_shown', questionId: questionId, timestamp: timestamp });
+                // The page's state when the question appears, so time away can be
+                // measured even if the user was already away.
+                this.pageEvents.push({ type: document.hidden ? 'hidden' : 'visible', questionId: questionId, timestamp: timestamp });
+                this.pageEvents.push({ type: document.hasFocus() ? 'focus' : 'blur', questionId: questionId, timestamp: timestamp });
+            }
+
             if (questionId) {
                 const observer = new IntersectionObserver((entries) => {
                     entries.forEach(entry => {
@@ -127,6 +199,9 @@ class InteractionTracker {
             };
             
             element.addEventListener('mouseover', () => this.currentTarget = targetData);
+            element.addEventListener('mouseout', () => {
+                if (this.currentTarget === targetData) this.currentTarget = null;
+            });
             element.addEventListener('click', (event) => this.handleInteraction(event, targetData));
             
             if (['input', 'textarea', 'select'].includes(element.tagName.toLowerCase())) {
@@ -135,7 +210,23 @@ class InteractionTracker {
         });
     }
     
+    // Device details of a pointer event: mouse, pen or touch, with pressure and contact size.
+    pointerDetails(event) {
+        return {
+            pointerType: event.pointerType || 'mouse',
+            pressure: event.pressure || 0,
+            contactWidth: event.width || 0,
+            contactHeight: event.height || 0
+        };
+    }
+
     handleMouseMove(event) {
+        const touch = this.activeTouches.get(event.pointerId);
+        if (touch) {
+            touch.endX = event.clientX;
+            touch.endY = event.clientY;
+        }
+
         const now = performance.now();
         if (now - this.lastRecordedTime < this.throttleInterval) return;
         
@@ -145,40 +236,195 @@ class InteractionTracker {
             y: event.clientY,
             timestamp: now - this.startTime,
             targetId: this.currentTarget?.id,
-            questionId: this.currentQuestion
+            questionId: this.currentQuestion,
+            ...this.pointerDetails(event)
+        });
+    }
+
+    handlePointerDown(event) {
+        const now = performance.now();
+        this.lastPointerDown = { ...this.pointerDetails(event), time: now };
+
+        if (event.pointerType !== 'touch') return;
+
+        const gesture = {
+            questionId: this.currentQuestion,
+            targetId: this.currentTarget?.id,
+            startX: event.clientX,
+            startY: event.clientY,
+            endX: event.clientX,
+            endY: event.clientY,
+            startTime: now,
+            pointerCount: this.activeTouches.size + 1,
+            ...this.pointerDetails(event)
+        };
+        this.activeTouches.set(event.pointerId, gesture);
+        // Every finger already down is now part of a multi-finger touch.
+        this.activeTouches.forEach(g => g.pointerCount = Math.max(g.pointerCount, this.activeTouches.size));
+    }
+
+    handlePointerUp(event) {
+        const now = performance.now();
+        if (this.lastPointerDown) {
+            this.lastPointerDown.duration = now - this.lastPointerDown.time;
+        }
+
+        const gesture = this.activeTouches.get(event.pointerId);
+        if (!gesture) return;
+        this.activeTouches.delete(event.pointerId);
+
+        gesture.endX = event.clientX || gesture.endX;
+        gesture.endY = event.clientY || gesture.endY;
+        const distance = Math.hypot(gesture.endX - gesture.startX, gesture.endY - gesture.startY);
+        const { startTime, ...rest } = gesture;
+
+        this.touchGestures.push({
+            ...rest,
+            type: distance < 10 ? 'tap' : 'swipe', // 10px of drift still counts as a tap
+            timestamp: startTime - this.startTime,
+            duration: now - startTime
         });
     }
     
-    handleKeyDown(event) {
-        if (['Control', 'Shift', 'Alt', 'Meta'].includes(event.key)) return;
-        
-        this.keyboardEvents.push({
-            type: 'keydown',
-            key: event.key,
+    // Reduce a key to its category; must match metrics.KeyCategory on the server.
+    static keyCategory(key) {
+        switch (key) {
+            case ' ': case 'Spacebar': case 'Space': case 'Enter':
+                return 'space';
+            case 'Backspace':
+                return 'backspace';
+            case 'Delete':
+                return 'delete';
+            case 'ArrowLeft': case 'ArrowRight': case 'ArrowUp': case 'ArrowDown':
+            case 'Home': case 'End': case 'PageUp': case 'PageDown': case 'Tab':
+                return 'navigation';
+            case 'Shift': case 'Control': case 'Alt': case 'AltGraph': case 'Meta': case 'CapsLock': case 'Fn':
+                return 'modifier';
+        }
+        return [...key].length === 1 ? 'character' : 'other';
+    }
+
+    // Build a keyboard event. Unless raw capture is enabled, only the key's category
+    // is recorded. Press IDs (keyed by the physical key code, which is never sent)
+    // let the server pair each keyup with its keydown.
+    keyboardEvent(type, event) {
+        let pressId;
+        if (type === 'keydown') {
+            pressId = this.activePresses.get(event.code) || ++this.nextPressId;
+            this.activePresses.set(event.code, pressId);
+        } else {
+            pressId = this.activePresses.get(event.code);
+            this.activePresses.delete(event.code);
+        }
+
+        const record = {
+            type: type,
+            category: InteractionTracker.keyCategory(event.key),
+            pressId: pressId,
             isModifier: event.ctrlKey || event.shiftKey || event.altKey || event.metaKey,
             timestamp: performance.now() - this.startTime,
             questionId: this.currentQuestion
-        });
+        };
+        if (this.keystrokeCapture === 'raw') {
+            record.key = event.key;
+        }
+        return record;
+    }
+
+    handleKeyDown(event) {
+        if (['Control', 'Shift', 'Alt', 'Meta'].includes(event.key)) return;
+        this.keyboardEvents.push(this.keyboardEvent('keydown', event));
     }
     
     handleKeyUp(event) {
         if (['Control', 'Shift', 'Alt', 'Meta'].includes(event.key)) return;
-        
-        this.keyboardEvents.push({
-            type: 'keyup',
-            key: event.key,
-            isModifier: event.ctrlKey || event.shiftKey || event.altKey || event.metaKey,
-            timestamp: performance.now() - this.startTime,
-            questionId: this.currentQuestion
-        });
+        this.keyboardEvents.push(this.keyboardEvent('keyup', event));
     }
     
+    // Record answer selections on radio buttons and drop-downs. Drop-down options are
+    // identified by index so option values are never sent.
+    handleChange(event) {
+        const element = event.target;
+        const section = element.closest('.form-group');
+        if (!section || !element.dataset.targetId) return;
+
+        let targetId;
+        if (element.tagName.toLowerCase() === 'select') {
+            targetId = `${element.dataset.targetId}:${element.selectedIndex}`;
+        } else if (element.type === 'radio') {
+            targetId = element.dataset.targetId;
+        } else {
+            return;
+        }
+
+        this.pageEvents.push({
+            type: 'option_selected',
+            questionId: section.dataset.questionId,
+            targetId: targetId,
+            timestamp: performance.now() - this.startTime
+        });
+    }
+
+    // Close the view of every question on screen.
+    leaveQuestions() {
+        const timestamp = performance.now() - this.startTime;
+        this.openQuestions.forEach(questionId => {
+            this.pageEvents.push({ type: 'question_left', questionId: questionId, timestamp: timestamp });
+        });
+        this.openQuestions.clear();
+    }
+
+    // Record a visibility or focus change against every question on screen, since
+    // all of them are interrupted. Outside a question it is recorded globally.
+    recordPageState(type) {
+        const timestamp = performance.now() - this.startTime;
+        if (this.openQuestions.size === 0) {
+            this.pageEvents.push({ type: type, timestamp: timestamp });
+            return;
+        }
+        this.openQuestions.forEach(questionId => {
+            this.pageEvents.push({ type: type, questionId: questionId, timestamp: timestamp });
+        });
+    }
+
+    handleResize() {
+        const now = performance.now();
+        if (now - this.lastResizeTime < this.pageEventInterval) return;
+        this.lastResizeTime = now;
+
+        this.pageEvents.push({
+            type: 'resize',
+            questionId: this.currentQuestion,
+            timestamp: now - this.startTime,
+            width: window.innerWidth,
+            height: window.innerHeight
+        });
+    }
+
+    handleScroll() {
+        const now = performance.now();
+        if (now - this.lastScrollTime < this.pageEventInterval) return;
+        this.lastScrollTime = now;
+
+        this.pageEvents.push({
+            type: 'scroll',
+            questionId: this.currentQuestion,
+            timestamp: now - this.startTime,
+            value: window.scrollY
+        });
+    }
+
     handleInteraction(event, targetData) {
         const rect = event.target.getBoundingClientRect();
         const timestamp = performance.now() - this.startTime;
         
         targetData.x = rect.left + rect.width/2;
         targetData.y = rect.top + rect.height/2;
+
+        // Clicks from the keyboard or assistive tech have no pointer press behind them.
+        const press = this.lastPointerDown && timestamp - (this.lastPointerDown.time - this.startTime) < 1000
+            ? this.lastPointerDown
+            : null;
         
         this.interactions.push({
             targetId: targetData.id,
@@ -188,7 +434,14 @@ class InteractionTracker {
             clickY: event.clientY,
             targetX: targetData.x,
             targetY: targetData.y,
-            timestamp: timestamp
+            targetWidth: rect.width,
+            targetHeight: rect.height,
+            timestamp: timestamp,
+            duration: press?.duration || 0,
+            pointerType: press?.pointerType || 'mouse',
+            pressure: press?.pressure || 0,
+            contactWidth: press?.contactWidth || 0,
+            contactHeight: press?.contactHeight || 0
         });
     }
     
@@ -197,12 +450,73 @@ class InteractionTracker {
             movements: this.movements,
             interactions: this.interactions,
             keyboardEvents: this.keyboardEvents,
+            gestures: this.touchGestures,
+            pageEvents: this.pageEvents,
             startTime: this.startTime
         };
     }
+
+    // The assessment being taken on the current page, or null outside an assessment.
+    currentAssessmentId() {
+        const form = document.querySelector('[data-assessment-id]');
+        return form ? parseInt(form.dataset.assessmentId, 10) : null;
+    }
+
+    // Split the buffered events into one chunk per question of the current assessment.
+    buildChunks(assessmentId) {
+        const byQuestion = new Map();
+        const bucket = (questionId) => {
+            const key = questionId || '';
+            if (!byQuestion.has(key)) {
+                byQuestion.set(key, { movements: [], interactions: [], keyboardEvents: [], gestures: [], pageEvents: [] });
+            }
+            return byQuestion.get(key);
+        };
+
+        this.movements.forEach(m => bucket(m.questionId).movements.push(m));
+        this.interactions.forEach(i => bucket(i.questionId).interactions.push(i));
+        this.keyboardEvents.forEach(k => bucket(k.questionId).keyboardEvents.push(k));
+        this.touchGestures.forEach(g => bucket(g.questionId).gestures.push(g));
+        this.pageEvents.forEach(e => bucket(e.questionId).pageEvents.push(e));
+
+        return Array.from(byQuestion, ([questionId, events]) => ({
+            assessmentId: assessmentId,
+            sessionId: this.sessionId,
+            seq: this.nextSeq++,
+            sessionStart: this.sessionStart,
+            questionId: questionId || undefined,
+            ...events
+        }));
+    }
+
+    loadPendingChunks() {
+        try {
+            return JSON.parse(sessionStorage.getItem('interactionTracker.pending')) || [];
+        } catch (e) {
+            return [];
+        }
+    }
+
+    savePendingChunks() {
+        try {
+            sessionStorage.setItem('interactionTracker.pending', JSON.stringify(this.pendingChunks));
+        } catch (e) {
+            console.warn('Could not persist pending interaction chunks:', e);
+        }
+    }
     
     sendData() {
-        if (this.movements.length === 0 && this.interactions.length === 0 && this.keyboardEvents.length === 0) {
+        if (this.movements.length > 0 || this.interactions.length > 0 || this.keyboardEvents.length > 0 || this.touchGestures.length > 0 || this.pageEvents.length > 0) {
+            // Events recorded outside an assessment can't be attributed and are dropped.
+            const assessmentId = this.currentAssessmentId();
+            if (assessmentId) {
+                this.pendingChunks.push(...this.buildChunks(assessmentId));
+                this.savePendingChunks();
+            }
+            this.reset();
+        }
+
+        if (this.pendingChunks.length === 0) {
             return;
         }
 
@@ -213,32 +527,48 @@ class InteractionTracker {
             console.warn("CSRF token not found. Metric will not be sent.");
         }
 
-        const data = this.getData();
-        // Use `navigator.sendBeacon` for reliability when the page is unloading.
-        // Fall back to fetch for other cases.
-        const blob = new Blob([JSON.stringify(data)], { type: 'application/json' });
+        // Everything still pending is (re)sent; the server acknowledges chunks it
+        // already has without storing them twice.
+        const sent = this.pendingChunks.slice();
+        const body = sent.map(chunk => JSON.stringify(chunk)).join('\n');
 
         fetch('/metrics', {
             method: 'POST',
             headers: {
-                'Content-Type': 'application/json',
+                'Content-Type': 'application/x-ndjson',
                 'X-CSRF-Token': csrfToken // Include CSRF token for security
             },
-            body: blob,
+            body: body,
             keepalive: true // Ensure the request completes even if the page is unloading
-        }).catch(error => {
-            console.error('Error sending metrics:', error);
-        });
-
-        // --- Reset after sending ---
-        this.reset();
+        })
+            .then(response => {
+                if (response.status === 400 || response.status === 413) {
+                    // The server will never accept these chunks, so retrying them
+                    // would only resend the same rejected request forever.
+                    this.pendingChunks = this.pendingChunks.filter(chunk => !sent.includes(chunk));
+                    this.savePendingChunks();
+                    return Promise.reject(new Error(`Interaction data rejected: HTTP ${response.status}`));
+                }
+                return response.ok ? response.json() : Promise.reject(new Error(`HTTP ${response.status}`));
+            })
+            .then(({ acked }) => {
+                const ackedKeys = new Set((acked || []).map(a => `${a.sessionId}:${a.seq}`));
+                this.pendingChunks = this.pendingChunks.filter(chunk => !ackedKeys.has(`${chunk.sessionId}:${chunk.seq}`));
+                this.savePendingChunks();
+            })
+            .catch(error => {
+                console.error('Error sending metrics:', error);
+            });
     }
     
+    // Clear the event buffers. Timestamps stay relative to the session start so
+    // chunks from the same session share one clock.
     reset() {
         this.movements = [];
         this.interactions = [];
         this.keyboardEvents = [];
-        this.startTime = performance.now();
+        this.touchGestures = [];
+        this.pageEvents = [];
     }
 }
 
@@ -250,6 +580,7 @@ if (!window.interactionTracker) {
 // Send any final data when the user closes the tab or navigates away from the site
 window.addEventListener('beforeunload', () => {
     if (window.interactionTracker) {
+        window.interactionTracker.leaveQuestions();
         window.interactionTracker.sendData();
         window.interactionTracker.cleanup();
     }
@@ -15,12 +15,18 @@ function initTMT(containerId, settings, onTestEnd) {
         partAItems: 15,
         partBItems: 15,
         includePartB: true,
+        practiceTrials: 5,
+        practiceEnabled: true,
         ...settings,
     };
     
     const partAItems = parseInt(testSettings.partAItems, 10);
     const partBItems = parseInt(testSettings.partBItems, 10);
     const includePartB = testSettings.includePartB === 'true';
+    const partATimeLimit = parseInt(testSettings.partATimeLimit, 10) || 0;
+    const partBTimeLimit = parseInt(testSettings.partBTimeLimit, 10) || 0;
+    const practiceItems = parseInt(testSettings.practiceTrials, 10) || 0;
+    const practiceEnabled = (testSettings.practiceEnabled === true || testSettings.practiceEnabled === 'true') && practiceItems > 0;
 
     // --- State Variables ---
     let phase = 'idle'; // idle, practice, part_a, part_b
@@ -29,20 +35,29 @@ function initTMT(containerId, settings, onTestEnd) {
     let currentItemIndex = 0;
     let errors = 0;
     let partStartTime = 0;
+    let partTimeoutRef = null;
     const canvasSize = { width: 600, height: 450 };
     const testData = {
         testStartTime: 0,
         testEndTime: 0,
+        // Part start and end times share the clock of the clicks, so the server
+        // can time each part from its clicks.
+        partAStartTime: 0,
+        partAEndTime: 0,
+        partBStartTime: 0,
+        partBEndTime: 0,
         partACompletionTime: 0,
         partAErrors: 0,
         partBCompletionTime: 0,
         partBErrors: 0,
+        timedOut: false,
         clicks: [],
         settings: testSettings,
     };
+    const focusMonitor = createFocusMonitor(testData);
 
     function generateItems() {
-        const numItems = currentPart === 'Practice' ? 5 : (currentPart === 'Part A' ? partAItems : partBItems);
+        const numItems = currentPart === 'Practice' ? practiceItems : (currentPart === 'Part A' ? partAItems : partBItems);
         items = [];
         const radius = 20;
         const minDistance = radius * 3.5;
@@ -114,28 +129,46 @@ function initTMT(containerId, settings, onTestEnd) {
         const y = e.clientY - rect.top;
 
         const clickedItem = items.find(item => Math.sqrt((x - item.x)**2 + (y - item.y)**2) <= item.radius);
-        testData.clicks.push({ x, y, time: performance.now() - testData.testStartTime, part: currentPart });
+        testData.clicks.push({
+            x,
+            y,
+            time: performance.now() - testData.testStartTime,
+            targetItem: clickedItem ? clickedItem.id : -1,
+            currentPart: currentPart,
+            isPractice: currentPart === 'Practice',
+        });
 
         if (clickedItem) {
             if (clickedItem.id === currentItemIndex) {
                 clickedItem.connected = true;
                 currentItemIndex++;
+                showPracticeFeedback('');
                 if (currentItemIndex >= items.length) {
                     endPart();
                 }
             } else if (!clickedItem.connected) {
                 errors++;
                 document.getElementById('tmt-errors').textContent = errors;
+                showPracticeFeedback(`Not quite - look for ${items[currentItemIndex].label}.`);
             }
         }
         draw();
     }
 
+    // Practice gives corrective feedback; scored parts only count errors.
+    function showPracticeFeedback(message) {
+        if (currentPart !== 'Practice') return;
+        const feedbackEl = document.getElementById('tmt-feedback');
+        if (feedbackEl) feedbackEl.textContent = message;
+    }
+
     function startPart(partName) {
         phase = 'active';
         currentPart = partName;
         errors = 0;
         partStartTime = performance.now();
+        if (partName === 'Part A') testData.partAStartTime = partStartTime - testData.testStartTime;
+        if (partName === 'Part B') testData.partBStartTime = partStartTime - testData.testStartTime;
         
         container.innerHTML = `
             <div class="flex justify-between items-center mb-2">
@@ -143,17 +176,44 @@ function initTMT(containerId, settings, onTestEnd) {
                 <div class="text-lg">Errors: <span id="tmt-errors">0</span></div>
             </div>
             <canvas id="tmt-canvas" width="${canvasSize.width}" height="${canvasSize.height}" class="bg-gray-100 rounded-md border-2 border-gray-300"></canvas>
+            ${currentPart === 'Practice' ? '<p id="tmt-feedback" class="mt-2 h-6 font-semibold text-red-700"></p>' : ''}
         `;
         document.getElementById('tmt-canvas').addEventListener('click', handleCanvasClick);
         generateItems();
         draw();
+
+        if (partName === 'Part A' || partName === 'Part B') {
+            if (partName === 'Part A') focusMonitor.start();
+            const limit = partName === 'Part A' ? partATimeLimit : partBTimeLimit;
+            if (limit > 0) partTimeoutRef = setTimeout(timeOutPart, limit);
+        }
+    }
+
+    // A part that runs past its time limit ends the test and is reported as timed out.
+    function timeOutPart() {
+        testData.timedOut = true;
+        const now = performance.now();
+        const completionTime = now - partStartTime;
+        if (currentPart === 'Part A') {
+            testData.partAEndTime = now - testData.testStartTime;
+            testData.partACompletionTime = completionTime;
+            testData.partAErrors = errors;
+        } else {
+            testData.partBEndTime = now - testData.testStartTime;
+            testData.partBCompletionTime = completionTime;
+            testData.partBErrors = errors;
+        }
+        endTest();
     }
 
     function endPart() {
-        const completionTime = performance.now() - partStartTime;
+        clearTimeout(partTimeoutRef);
+        const now = performance.now();
+        const completionTime = now - partStartTime;
         if (currentPart === 'Practice') {
             startPart('Part A');
         } else if (currentPart === 'Part A') {
+            testData.partAEndTime = now - testData.testStartTime;
             testData.partACompletionTime = completionTime;
             testData.partAErrors = errors;
             if (includePartB) {
@@ -162,6 +222,7 @@ function initTMT(containerId, settings, onTestEnd) {
                 endTest();
             }
         } else { // Part B
+            testData.partBEndTime = now - testData.testStartTime;
             testData.partBCompletionTime = completionTime;
             testData.partBErrors = errors;
             endTest();
@@ -170,6 +231,8 @@ function initTMT(containerId, settings, onTestEnd) {
 
     function endTest() {
         phase = 'idle';
+        clearTimeout(partTimeoutRef);
+        focusMonitor.stop();
         testData.testEndTime = performance.now();
         container.innerHTML = `<p class="text-lg font-semibold">Test complete. Saving results...</p>`;
         if (onTestEnd) onTestEnd(testData);
@@ -178,6 +241,6 @@ function initTMT(containerId, settings, onTestEnd) {
     container.innerHTML = `<button id="start-tmt-btn" class="primary-button">Start Test</button>`;
     document.getElementById('start-tmt-btn').onclick = () => {
         testData.testStartTime = performance.now();
-        startPart('Practice');
+        startPart(practiceEnabled ? 'Practice' : 'Part A');
     };
 }
\ No newline at end of file
@@ -0,0 +1,155 @@
+// server/internal/cognitive/cognitive.go
+package cognitive
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"crapp-go/internal/models"
+	"crapp-go/internal/validity"
+
+	"gorm.io/gorm"
+)
+
+// CognitiveTest describes a cognitive test that can be embedded in an assessment.
+// A question whose type matches Type() is handled by the registered test, so new
+// tests plug in by implementing this interface and calling Register.
+type CognitiveTest interface {
+	// Type is the question type used in questions.yaml (e.g. "cpt").
+	Type() string
+	// Title is the human-readable name of the test.
+	Title() string
+	// Parse decodes the JSON payload submitted by the client-side test.
+	Parse(payload []byte) (Submission, error)
+	// AlgorithmVersion is the version of the scoring algorithm stamped on new results.
+	AlgorithmVersion() int
+	// ResultsTable is the table holding one summary row per completed run.
+	ResultsTable() string
+	// Metrics lists the chartable metrics produced by the test.
+	Metrics() []models.MetricOption
+	// ChartSQL returns one or more SELECT statements, joined with UNION ALL, that yield
+	// (row_id, assessment_id, created_at, question_id, metric_key, metric_value,
+	// validity_status, algorithm_version) rows. Each statement must keep to the
+	// assessments listed in the scoped_assessments CTE defined by the chart query.
+	ChartSQL() string
+	// Models returns the GORM models that must be migrated for the test.
+	Models() []interface{}
+}
+
+// Submission is a single parsed run of a cognitive test.
+type Submission interface {
+	// Validate checks that the payload contains enough data to be scored.
+	Validate() error
+	// Score calculates the summary metrics for the run.
+	Score(assessmentID uint) error
+	// Facts returns the scored values and run properties that validity rules check.
+	Facts() map[string]float64
+	// SetValidity records the outcome of the validity rules on the summary row.
+	SetValidity(v models.Validity)
+	// MarkReprocessed links the summary to the result it was recomputed from and
+	// keeps the original test date.
+	MarkReprocessed(sourceID uint, takenAt time.Time)
+	// Save persists the scored summary and its trial-level rows.
+	Save(tx *gorm.DB) error
+}
+
+// Process parses, validates and scores a raw client payload, then checks the run
+// against the validity rules. The returned submission is ready to be saved.
+func Process(test CognitiveTest, assessmentID uint, payload []byte) (Submission, error) {
+	submission, err := test.Parse(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s data: %w", test.Type(), err)
+	}
+	if err := submission.Validate(); err != nil {
+		return nil, err
+	}
+	if err := submission.Score(assessmentID); err != nil {
+		return nil, err
+	}
+	submission.SetValidity(validity.Evaluate(test.Type(), submission.Facts()))
+	return submission, nil
+}
+
+var (
+	registry = make(map[string]CognitiveTest)
+	order    []string
+)
+
+// Register makes a cognitive test available to the handlers. It panics if a
+// test with the same type has already been registered.
+func Register(test CognitiveTest) {
+	if _, exists := registry[test.Type()]; exists {
+		panic(fmt.Sprintf("cognitive: test %q registered twice", test.Type()))
+	}
+	registry[test.Type()] = test
+	order = append(order, test.Type())
+}
+
+// Get returns the test registered for the given question type.
+func Get(questionType string) (CognitiveTest, bool) {
+	test, ok := registry[questionType]
+	return test, ok
+}
+
+// IsCognitive reports whether a question type is handled by a cognitive test.
+func IsCognitive(questionType string) bool {
+	_, ok := registry[questionType]
+	return ok
+}
+
+// All returns every registered test in registration order.
+func All() []CognitiveTest {
+	tests := make([]CognitiveTest, 0, len(order))
+	for _, t := range order {
+		tests = append(tests, registry[t])
+	}
+	return tests
+}
+
+// Models returns the GORM models of every registered test.
+func Models() []interface{} {
+	var all []interface{}
+	for _, test := range All() {
+		all = append(all, test.Models()...)
+	}
+	return all
+}
+
+// ChartSQL combines the chart queries of every registered test.
+func ChartSQL() string {
+	parts := make([]string, 0, len(order))
+	for _, test := range All() {
+		parts = append(parts, strings.TrimSpace(test.ChartSQL()))
+	}
+	return strings.Join(parts, "\n\n\t\tUNION ALL\n\n\t\t")
+}
+
+// unionAll joins chart SELECT statements into a single query.
+func unionAll(selects ...string) string {
+	return strings.Join(selects, " UNION ALL\n\t\t")
+}
+
+// withFacts merges run facts into a single map for validity evaluation.
+func withFacts(facts ...map[string]float64) map[string]float64 {
+	merged := make(map[string]float64)
+	for _, f := range facts {
+		for k, v := range f {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// metricSelect builds a chart query selecting one column of a results table as a
+// metric, for the assessments in scope.
+func metricSelect(questionID, metricKey, column, table string) string {
+	return fmt.Sprintf("SELECT id AS row_id, assessment_id, created_at, '%s' AS question_id, '%s' AS metric_key, %s AS metric_value, validity_status, algorithm_version FROM %s WHERE deleted_at IS NULL AND assessment_id IN (SELECT id FROM scoped_assessments)",
+		questionID, metricKey, column, table)
+}
+
+// LatestResults selects the most recent result per assessment from a results table,
+// so reprocessed runs replace the originals without the originals being deleted.
+func LatestResults(table string) string {
+	return fmt.Sprintf("(SELECT DISTINCT ON (assessment_id) * FROM %s WHERE deleted_at IS NULL ORDER BY assessment_id, id DESC)", table)
+}
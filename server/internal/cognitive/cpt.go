@@ -0,0 +1,142 @@
+// server/internal/cognitive/cpt.go
+package cognitive
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"crapp-go/internal/metrics"
+	"crapp-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(cptTest{})
+}
+
+// cptTest is the Continuous Performance Test.
+type cptTest struct{}
+
+func (cptTest) Type() string  { return "cpt" }
+func (cptTest) Title() string { return "Continuous Performance Test" }
+
+func (cptTest) AlgorithmVersion() int { return metrics.CPTAlgorithmVersion }
+
+func (cptTest) ResultsTable() string { return "cpt_results" }
+
+func (cptTest) Parse(payload []byte) (Submission, error) {
+	var data metrics.CPTData
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+	return &cptSubmission{raw: payload, data: &data}, nil
+}
+
+func (cptTest) Metrics() []models.MetricOption {
+	return []models.MetricOption{
+		{Value: "reaction_time", Label: "Reaction Time (ms)"},
+		{Value: "detection_rate", Label: "Detection Rate (%)"},
+		{Value: "omission_error_rate", Label: "Omission Error Rate (%)"},
+		{Value: "commission_error_rate", Label: "Commission Error Rate (%)"},
+	}
+}
+
+func (t cptTest) ChartSQL() string {
+	return unionAll(
+		metricSelect(t.Type(), "reaction_time", "average_reaction_time", t.ResultsTable()),
+		metricSelect(t.Type(), "detection_rate", "detection_rate", t.ResultsTable()),
+		metricSelect(t.Type(), "omission_error_rate", "omission_error_rate", t.ResultsTable()),
+		metricSelect(t.Type(), "commission_error_rate", "commission_error_rate", t.ResultsTable()),
+	)
+}
+
+func (cptTest) Models() []interface{} {
+	return []interface{}{&models.CPTResult{}, &models.CPTEvent{}}
+}
+
+type cptSubmission struct {
+	raw     json.RawMessage // Original client payload, stored verbatim
+	data    *metrics.CPTData
+	summary models.CPTResult
+	events  []models.CPTEvent
+}
+
+func (s *cptSubmission) Validate() error {
+	if len(s.data.StimuliPresented) == 0 {
+		return errors.New("cpt: no stimuli were presented")
+	}
+	return nil
+}
+
+func (s *cptSubmission) Score(assessmentID uint) error {
+	data := s.data
+	s.summary = models.CPTResult{
+		AssessmentID:        assessmentID,
+		RawData:             s.raw,
+		Provenance:          models.Provenance{AlgorithmVersion: metrics.CPTAlgorithmVersion},
+		CorrectDetections:   metrics.CountCorrectDetections(data),
+		CommissionErrors:    metrics.CountCommissionErrors(data),
+		OmissionErrors:      metrics.CountOmissionErrors(data),
+		AverageReactionTime: metrics.CalculateAverageReactionTime(data),
+		ReactionTimeSD:      metrics.CalculateReactionTimeSD(data),
+		DetectionRate:       metrics.CalculateDetectionRate(data),
+		OmissionErrorRate:   metrics.CalculateOmissionErrorRate(data),
+		CommissionErrorRate: metrics.CalculateCommissionErrorRate(data),
+	}
+
+	s.events = nil
+	for _, stim := range data.StimuliPresented {
+		st := stim // Local copy for pointer safety
+		s.events = append(s.events, models.CPTEvent{
+			EventType:     "stimulus",
+			StimulusValue: &st.Value,
+			IsTarget:      &st.IsTarget,
+			PresentedAt:   &st.PresentedAt,
+			IsPractice:    st.IsPractice,
+		})
+	}
+	for _, resp := range data.Responses {
+		r := resp // Local copy for pointer safety
+		s.events = append(s.events, models.CPTEvent{
+			EventType:     "response",
+			StimulusValue: &r.Stimulus,
+			IsTarget:      &r.IsTarget,
+			ResponseTime:  &r.ResponseTime,
+			StimulusIndex: &r.StimulusIndex,
+			IsPractice:    r.IsPractice,
+		})
+	}
+	return nil
+}
+
+func (s *cptSubmission) Facts() map[string]float64 {
+	return withFacts(map[string]float64{
+		"stimulus_count":        float64(len(s.data.StimuliPresented)),
+		"response_count":        float64(s.summary.CorrectDetections + s.summary.CommissionErrors),
+		"reaction_time":         s.summary.AverageReactionTime,
+		"detection_rate":        s.summary.DetectionRate,
+		"omission_error_rate":   s.summary.OmissionErrorRate,
+		"commission_error_rate": s.summary.CommissionErrorRate,
+	}, s.data.FocusData.Facts())
+}
+
+func (s *cptSubmission) SetValidity(v models.Validity) {
+	s.summary.Validity = v
+}
+
+func (s *cptSubmission) MarkReprocessed(sourceID uint, takenAt time.Time) {
+	s.summary.ReprocessedFromID = &sourceID
+	s.summary.CreatedAt = takenAt
+}
+
+func (s *cptSubmission) Save(tx *gorm.DB) error {
+	if err := tx.Create(&s.summary).Error; err != nil {
+		return err
+	}
+	for i := range s.events {
+		s.events[i].ResultID = s.summary.ID
+	}
+	return tx.Create(&s.events).Error
+}
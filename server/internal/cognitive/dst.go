@@ -0,0 +1,126 @@
+// server/internal/cognitive/dst.go
+package cognitive
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"crapp-go/internal/metrics"
+	"crapp-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(dstTest{})
+}
+
+// dstTest is the Digit Span Test.
+type dstTest struct{}
+
+func (dstTest) Type() string  { return "dst" }
+func (dstTest) Title() string { return "Digit Span Test" }
+
+func (dstTest) AlgorithmVersion() int { return metrics.DSTAlgorithmVersion }
+
+func (dstTest) ResultsTable() string { return "dst_results" }
+
+func (dstTest) Parse(payload []byte) (Submission, error) {
+	var data metrics.DigitSpanRawData
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+	return &dstSubmission{raw: payload, data: &data}, nil
+}
+
+func (dstTest) Metrics() []models.MetricOption {
+	return []models.MetricOption{
+		{Value: "highest_span", Label: "Highest Span Achieved"},
+		{Value: "correct_trials", Label: "Correct Trials"},
+		{Value: "total_trials", Label: "Total Trials"},
+	}
+}
+
+func (t dstTest) ChartSQL() string {
+	return unionAll(
+		metricSelect(t.Type(), "highest_span", "highest_span_achieved::float", t.ResultsTable()),
+		metricSelect(t.Type(), "correct_trials", "correct_trials::float", t.ResultsTable()),
+		metricSelect(t.Type(), "total_trials", "total_trials::float", t.ResultsTable()),
+	)
+}
+
+func (dstTest) Models() []interface{} {
+	return []interface{}{&models.DSTResult{}, &models.DSTAttempt{}}
+}
+
+type dstSubmission struct {
+	raw      json.RawMessage // Original client payload, stored verbatim
+	data     *metrics.DigitSpanRawData
+	summary  models.DSTResult
+	attempts []models.DSTAttempt
+}
+
+func (s *dstSubmission) Validate() error {
+	if len(s.data.Results) == 0 {
+		return errors.New("dst: no attempts were recorded")
+	}
+	return nil
+}
+
+func (s *dstSubmission) Score(assessmentID uint) error {
+	processed, err := metrics.CalculateDigitSpanMetrics(s.data)
+	if err != nil {
+		return err
+	}
+	s.summary = models.DSTResult{
+		AssessmentID:        assessmentID,
+		RawData:             s.raw,
+		Provenance:          models.Provenance{AlgorithmVersion: metrics.DSTAlgorithmVersion},
+		HighestSpanAchieved: processed.HighestSpanAchieved,
+		TotalTrials:         processed.TotalTrials,
+		CorrectTrials:       processed.CorrectTrials,
+		CreatedAt:           time.Now(),
+	}
+
+	s.attempts = make([]models.DSTAttempt, len(s.data.Results))
+	for i, attempt := range s.data.Results {
+		s.attempts[i] = models.DSTAttempt{
+			Span:       attempt.Span,
+			Trial:      attempt.Trial,
+			Sequence:   attempt.Sequence,
+			Input:      attempt.Input,
+			IsCorrect:  attempt.Correct,
+			Timestamp:  attempt.Timestamp,
+			IsPractice: attempt.IsPractice,
+		}
+	}
+	return nil
+}
+
+func (s *dstSubmission) Facts() map[string]float64 {
+	return withFacts(map[string]float64{
+		"highest_span":   float64(s.summary.HighestSpanAchieved),
+		"correct_trials": float64(s.summary.CorrectTrials),
+		"total_trials":   float64(s.summary.TotalTrials),
+	}, s.data.FocusData.Facts())
+}
+
+func (s *dstSubmission) SetValidity(v models.Validity) {
+	s.summary.Validity = v
+}
+
+func (s *dstSubmission) MarkReprocessed(sourceID uint, takenAt time.Time) {
+	s.summary.ReprocessedFromID = &sourceID
+	s.summary.CreatedAt = takenAt
+}
+
+func (s *dstSubmission) Save(tx *gorm.DB) error {
+	if err := tx.Create(&s.summary).Error; err != nil {
+		return err
+	}
+	for i := range s.attempts {
+		s.attempts[i].ResultID = s.summary.ID
+	}
+	return tx.Create(&s.attempts).Error
+}
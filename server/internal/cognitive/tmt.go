@@ -0,0 +1,135 @@
+// server/internal/cognitive/tmt.go
+package cognitive
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"crapp-go/internal/metrics"
+	"crapp-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	Register(tmtTest{})
+}
+
+// tmtTest is the Trail Making Test.
+type tmtTest struct{}
+
+func (tmtTest) Type() string  { return "tmt" }
+func (tmtTest) Title() string { return "Trail Making Test" }
+
+func (tmtTest) AlgorithmVersion() int { return metrics.TMTAlgorithmVersion }
+
+func (tmtTest) ResultsTable() string { return "tmt_results" }
+
+func (tmtTest) Parse(payload []byte) (Submission, error) {
+	var data metrics.TrailMakingData
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+	return &tmtSubmission{raw: payload, data: &data}, nil
+}
+
+func (tmtTest) Metrics() []models.MetricOption {
+	return []models.MetricOption{
+		{Value: "part_a_time", Label: "Part A Time (ms)"},
+		{Value: "part_b_time", Label: "Part B Time (ms)"},
+		{Value: "b_a_ratio", Label: "B/A Ratio"},
+		{Value: "part_a_errors", Label: "Part A Errors"},
+		{Value: "part_b_errors", Label: "Part B Errors"},
+	}
+}
+
+func (t tmtTest) ChartSQL() string {
+	return unionAll(
+		metricSelect(t.Type(), "part_a_time", "part_a_completion_time", t.ResultsTable()),
+		metricSelect(t.Type(), "part_b_time", "part_b_completion_time", t.ResultsTable()),
+		metricSelect(t.Type(), "part_a_errors", "part_a_errors::float", t.ResultsTable()),
+		metricSelect(t.Type(), "part_b_errors", "part_b_errors::float", t.ResultsTable()),
+		metricSelect(t.Type(), "b_a_ratio", "b_to_a_ratio", t.ResultsTable()),
+	)
+}
+
+func (tmtTest) Models() []interface{} {
+	return []interface{}{&models.TMTResult{}, &models.TMTClick{}}
+}
+
+type tmtSubmission struct {
+	raw     json.RawMessage // Original client payload, stored verbatim
+	data    *metrics.TrailMakingData
+	summary models.TMTResult
+	clicks  []models.TMTClick
+}
+
+func (s *tmtSubmission) Validate() error {
+	if len(s.data.Clicks) == 0 {
+		return errors.New("tmt: no clicks were recorded")
+	}
+	return nil
+}
+
+func (s *tmtSubmission) Score(assessmentID uint) error {
+	processed := metrics.CalculateTrailMetrics(s.data)
+	s.summary = models.TMTResult{
+		AssessmentID:        assessmentID,
+		RawData:             s.raw,
+		Provenance:          models.Provenance{AlgorithmVersion: metrics.TMTAlgorithmVersion},
+		PartACompletionTime: processed.PartACompletionTime,
+		PartAErrors:         processed.PartAErrors,
+		PartBCompletionTime: processed.PartBCompletionTime,
+		PartBErrors:         processed.PartBErrors,
+		BToARatio:           processed.BToARatio,
+		CreatedAt:           time.Now(),
+	}
+
+	s.clicks = make([]models.TMTClick, len(s.data.Clicks))
+	for i, click := range s.data.Clicks {
+		s.clicks[i] = models.TMTClick{
+			X:           click.X,
+			Y:           click.Y,
+			Time:        click.Time,
+			TargetItem:  click.TargetItem,
+			CurrentPart: click.CurrentPart,
+			IsPractice:  click.IsPractice,
+		}
+	}
+	return nil
+}
+
+func (s *tmtSubmission) Facts() map[string]float64 {
+	timedOut := 0.0
+	if s.data.TimedOut {
+		timedOut = 1
+	}
+	return withFacts(map[string]float64{
+		"part_a_time":   s.summary.PartACompletionTime,
+		"part_b_time":   s.summary.PartBCompletionTime,
+		"part_a_errors": float64(s.summary.PartAErrors),
+		"part_b_errors": float64(s.summary.PartBErrors),
+		"b_a_ratio":     s.summary.BToARatio,
+		"timed_out":     timedOut,
+	}, s.data.FocusData.Facts())
+}
+
+func (s *tmtSubmission) SetValidity(v models.Validity) {
+	s.summary.Validity = v
+}
+
+func (s *tmtSubmission) MarkReprocessed(sourceID uint, takenAt time.Time) {
+	s.summary.ReprocessedFromID = &sourceID
+	s.summary.CreatedAt = takenAt
+}
+
+func (s *tmtSubmission) Save(tx *gorm.DB) error {
+	if err := tx.Create(&s.summary).Error; err != nil {
+		return err
+	}
+	for i := range s.clicks {
+		s.clicks[i].ResultID = s.summary.ID
+	}
+	return tx.Create(&s.clicks).Error
+}
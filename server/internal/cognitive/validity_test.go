@@ -0,0 +1,118 @@
+package cognitive
+
+import (
+	"slices"
+	"testing"
+
+	"crapp-go/internal/config"
+	"crapp-go/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// useDefaultRules loads the configuration defaults, as a server without a config
+// file would run, for the duration of the test.
+func useDefaultRules(t *testing.T) {
+	t.Helper()
+	previous := config.Conf
+	t.Cleanup(func() { config.Conf = previous })
+	if err := config.Init(t.TempDir(), zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// validityOf returns the validity recorded on a processed submission.
+func validityOf(t *testing.T, submission Submission) models.Validity {
+	t.Helper()
+	switch s := submission.(type) {
+	case *cptSubmission:
+		return s.summary.Validity
+	case *tmtSubmission:
+		return s.summary.Validity
+	case *dstSubmission:
+		return s.summary.Validity
+	}
+	t.Fatalf("unexpected submission type %T", submission)
+	return models.Validity{}
+}
+
+func TestProcessAppliesValidityRules(t *testing.T) {
+	useDefaultRules(t)
+
+	const (
+		cptStimuli  = `"stimuliPresented":[{"value":"X","isTarget":true,"presentedAt":1000},{"value":"A","isTarget":false,"presentedAt":2000}]`
+		cptResponse = `"responses":[{"stimulus":"X","isTarget":true,"responseTime":420,"stimulusIndex":0}]`
+		tmtClicks   = `"clicks":[{"x":10,"y":10,"time":500,"targetItem":1,"currentPart":"A"}]`
+		dstResults  = `"results":[{"span":3,"trial":1,"sequence":"123","input":"123","correct":true,"timestamp":100}]`
+	)
+	interrupted := "The page was hidden or lost focus while the test was on screen"
+
+	tests := []struct {
+		name     string
+		testType string
+		payload  string
+		want     []string
+	}{
+		{"cpt with responses", "cpt", `{` + cptStimuli + `,` + cptResponse + `}`, nil},
+		{"cpt without responses", "cpt", `{` + cptStimuli + `,"responses":[]}`, []string{"No responses were recorded"}},
+		{"cpt practice responses do not count", "cpt",
+			`{` + cptStimuli + `,"responses":[{"stimulus":"X","isTarget":true,"responseTime":420,"stimulusIndex":0,"isPractice":true}]}`,
+			[]string{"No responses were recorded"}},
+		{"tmt finished", "tmt", `{` + tmtClicks + `,"timedOut":false}`, nil},
+		{"tmt timed out", "tmt", `{` + tmtClicks + `,"timedOut":true}`, []string{"Test was abandoned at the time limit"}},
+		{"dst uninterrupted", "dst", `{` + dstResults + `,"focusLostCount":0}`, nil},
+		{"dst lost focus", "dst", `{` + dstResults + `,"focusLostCount":2,"timeHidden":3000}`, []string{interrupted}},
+		{"cpt with no responses and lost focus", "cpt", `{` + cptStimuli + `,"responses":[],"focusLostCount":1}`,
+			[]string{"No responses were recorded", interrupted}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, ok := Get(tt.testType)
+			if !ok {
+				t.Fatalf("test %q is not registered", tt.testType)
+			}
+			submission, err := Process(test, 1, []byte(tt.payload))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := validityOf(t, submission)
+			wantStatus := models.ValidityValid
+			if len(tt.want) > 0 {
+				wantStatus = models.ValidityInvalid
+			}
+			if got.ValidityStatus != wantStatus || !slices.Equal([]string(got.ValidityReasons), tt.want) {
+				t.Errorf("validity = %s %v, want %s %v", got.ValidityStatus, got.ValidityReasons, wantStatus, tt.want)
+			}
+		})
+	}
+}
+
+func TestFactsNameTheRuleFacts(t *testing.T) {
+	// Rules are matched to facts by name, so a renamed fact would silently
+	// disable its rule.
+	tests := []struct {
+		testType string
+		payload  string
+		facts    []string
+	}{
+		{"cpt", `{"stimuliPresented":[{"value":"X","isTarget":true}]}`, []string{"response_count", "interruption_count", "time_away"}},
+		{"tmt", `{"clicks":[{"targetItem":1,"currentPart":"A"}]}`, []string{"timed_out", "interruption_count", "time_away"}},
+		{"dst", `{"results":[{"span":3,"trial":1,"correct":true}]}`, []string{"highest_span", "interruption_count", "time_away"}},
+	}
+	for _, tt := range tests {
+		test, _ := Get(tt.testType)
+		submission, err := test.Parse([]byte(tt.payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := submission.Score(1); err != nil {
+			t.Fatal(err)
+		}
+		facts := submission.Facts()
+		for _, name := range tt.facts {
+			if _, ok := facts[name]; !ok {
+				t.Errorf("%s facts %v lack %q", tt.testType, facts, name)
+			}
+		}
+	}
+}
@@ -15,9 +15,12 @@ var Conf *Config
 
 // Config struct is the top-level configuration structure.
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	Logging     LoggingConfig     `mapstructure:"logging"`
+	Validity    ValidityConfig    `mapstructure:"validity"`
+	Interaction InteractionConfig `mapstructure:"interaction"`
+	Results     ResultsConfig     `mapstructure:"results"`
 }
 
 // ServerConfig holds server-related settings.
@@ -44,6 +47,90 @@ type LoggingConfig struct {
 	Compress   bool   `mapstructure:"compress"`
 }
 
+// ValidityConfig holds the rules used to flag invalid cognitive test runs.
+type ValidityConfig struct {
+	Rules []ValidityRule `mapstructure:"rules"`
+}
+
+// ValidityRule flags a run as invalid when one of its facts falls outside [Min, Max].
+type ValidityRule struct {
+	Test   string   `mapstructure:"test"` // Test type, or "*" for every test
+	Fact   string   `mapstructure:"fact"`
+	Min    *float64 `mapstructure:"min"`
+	Max    *float64 `mapstructure:"max"`
+	Reason string   `mapstructure:"reason"`
+}
+
+// Keystroke capture modes for InteractionConfig.KeystrokeCapture.
+const (
+	KeystrokeCategories = "categories" // Keys are reduced to categories before they leave the browser
+	KeystrokeRaw        = "raw"        // The actual keys are stored
+)
+
+// InteractionConfig holds settings for interaction data collection.
+type InteractionConfig struct {
+	KeystrokeCapture string            `mapstructure:"keystroke_capture"`
+	Limits           InteractionLimits `mapstructure:"limits"`
+}
+
+// InteractionLimits bounds the interaction data accepted on /metrics. Requests
+// over the size or event limits are rejected; individual events that fail the
+// plausibility limits are dropped and counted.
+type InteractionLimits struct {
+	MaxBodyBytes       int64   `mapstructure:"max_body_bytes"`
+	MaxChunkEvents     int     `mapstructure:"max_chunk_events"`
+	MaxSessionDuration float64 `mapstructure:"max_session_duration"` // Milliseconds since the tracker started
+	MaxCoordinate      float64 `mapstructure:"max_coordinate"`       // Pixels from the viewport origin
+	MaxVelocity        float64 `mapstructure:"max_velocity"`         // Pixels per second
+}
+
+// ResultsConfig holds settings for the results charts.
+type ResultsConfig struct {
+	// BaselineAssessments is how many of a user's first valid assessments make up
+	// their baseline until they choose a different window.
+	BaselineAssessments int                  `mapstructure:"baseline_assessments"`
+	ReliableChange      ReliableChangeConfig `mapstructure:"reliable_change"`
+	Trend               TrendConfig          `mapstructure:"trend"`
+	// MaxLagDays bounds the lag, in days, between the task metric and the symptom
+	// score on the correlation chart.
+	MaxLagDays int `mapstructure:"max_lag_days"`
+	// HistoryPageSize is how many past assessments each page of the history lists.
+	HistoryPageSize int `mapstructure:"history_page_size"`
+}
+
+// TrendConfig holds the defaults for the trend statistics on the timeline chart.
+type TrendConfig struct {
+	RollingWindow int     `mapstructure:"rolling_window"` // Assessments averaged by the rolling mean
+	Method        string  `mapstructure:"method"`         // "linear" or "loess"
+	LoessSpan     float64 `mapstructure:"loess_span"`     // Fraction of the points used for each LOESS fit
+	Confidence    float64 `mapstructure:"confidence"`     // Confidence level of the slope interval
+}
+
+// ReliableChangeConfig holds the settings for reliable change indices. A change
+// from baseline is reliable when the absolute index exceeds Threshold.
+type ReliableChangeConfig struct {
+	Threshold float64                `mapstructure:"threshold"`
+	Metrics   []ReliableChangeMetric `mapstructure:"metrics"`
+}
+
+// ReliableChangeMetric is the test-retest reliability of one cognitive test metric.
+type ReliableChangeMetric struct {
+	Test          string  `mapstructure:"test"`
+	Metric        string  `mapstructure:"metric"`
+	Reliability   float64 `mapstructure:"reliability"`
+	LowerIsBetter bool    `mapstructure:"lower_is_better"`
+}
+
+// Lookup returns the reliability settings of a test metric, if it has any.
+func (c ReliableChangeConfig) Lookup(test, metric string) (ReliableChangeMetric, bool) {
+	for _, m := range c.Metrics {
+		if m.Test == test && m.Metric == metric {
+			return m, true
+		}
+	}
+	return ReliableChangeMetric{}, false
+}
+
 // setDefaults sets the default values for the configuration.
 func setDefaults(v *viper.Viper) {
 	// Server defaults
@@ -63,6 +150,39 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("logging.max_backups", 3) // Keep 3 backups
 	v.SetDefault("logging.max_age", 7)     // 7 days
 	v.SetDefault("logging.compress", true) // Compress old logs
+
+	// Interaction defaults
+	v.SetDefault("interaction.keystroke_capture", KeystrokeCategories)
+	v.SetDefault("interaction.limits.max_body_bytes", 2<<20) // 2 MB
+	v.SetDefault("interaction.limits.max_chunk_events", 20000)
+	v.SetDefault("interaction.limits.max_session_duration", 4*60*60*1000) // 4 hours
+	v.SetDefault("interaction.limits.max_coordinate", 20000)
+	v.SetDefault("interaction.limits.max_velocity", 50000)
+
+	// Results defaults
+	v.SetDefault("results.baseline_assessments", 5)
+	v.SetDefault("results.reliable_change.threshold", 1.96) // 95% confidence
+	v.SetDefault("results.trend.rolling_window", 5)
+	v.SetDefault("results.trend.method", "linear")
+	v.SetDefault("results.trend.loess_span", 0.75)
+	v.SetDefault("results.trend.confidence", 0.95)
+	v.SetDefault("results.max_lag_days", 7)
+	v.SetDefault("results.history_page_size", 20)
+	v.SetDefault("results.reliable_change.metrics", []map[string]interface{}{
+		{"test": "cpt", "metric": "reaction_time", "reliability": 0.80, "lower_is_better": true},
+		{"test": "cpt", "metric": "omission_error_rate", "reliability": 0.70, "lower_is_better": true},
+		{"test": "cpt", "metric": "commission_error_rate", "reliability": 0.70, "lower_is_better": true},
+		{"test": "tmt", "metric": "part_a_time", "reliability": 0.79, "lower_is_better": true},
+		{"test": "tmt", "metric": "part_b_time", "reliability": 0.89, "lower_is_better": true},
+		{"test": "dst", "metric": "highest_span", "reliability": 0.80, "lower_is_better": false},
+	})
+
+	// Validity defaults
+	v.SetDefault("validity.rules", []map[string]interface{}{
+		{"test": "cpt", "fact": "response_count", "min": 1, "reason": "No responses were recorded"},
+		{"test": "tmt", "fact": "timed_out", "max": 0, "reason": "Test was abandoned at the time limit"},
+		{"test": "*", "fact": "interruption_count", "max": 0, "reason": "The page was hidden or lost focus while the test was on screen"},
+	})
 }
 
 // Init initializes the configuration with Viper.
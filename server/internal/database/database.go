@@ -1,6 +1,7 @@
 package database
 
 import (
+	"crapp-go/internal/cognitive"
 	"crapp-go/internal/config"
 	logging "crapp-go/internal/logging"
 	"crapp-go/internal/models"
@@ -39,18 +40,21 @@ func Init(log *zap.Logger) {
 func runMigrations(log *zap.Logger) {
 	// GORM's AutoMigrate will create tables, columns, and foreign keys.
 	// It will NOT create custom indexes, so we handle that separately.
-	err := DB.AutoMigrate(
+	coreModels := []interface{}{
 		&models.User{},
 		&models.AssessmentState{},
 		&models.Answer{},
 		&models.AssessmentMetric{},
-		&models.DSTResult{},
-		&models.CPTResult{},
-		&models.TMTResult{},
-		&models.DSTAttempt{},
-		&models.CPTEvent{},
-		&models.TMTClick{},
-	)
+		&models.InteractionChunk{},
+		&models.QuarantinedInteractionChunk{},
+		&models.InteractionEventStream{},
+		&models.Baseline{},
+		&models.NormativeEntry{},
+		&models.ChangeEvent{},
+		&models.ClinicianAccess{},
+	}
+	// Each registered cognitive test contributes its own result tables.
+	err := DB.AutoMigrate(append(coreModels, cognitive.Models()...)...)
 	if err != nil {
 		log.Fatal("Failed to run database migrations", zap.Error(err))
 	}
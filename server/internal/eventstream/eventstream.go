@@ -0,0 +1,375 @@
+// Package eventstream encodes raw interaction events into a compact binary form for
+// long-term storage and decodes them back into models.InteractionData.
+//
+// Events are stored column by column: every field of a stream is written as its
+// own run of values, so similar values sit next to each other and compress well.
+// Timestamps and coordinates are fixed-point integers (microseconds and hundredths
+// of a pixel) written as zigzag varint deltas from the previous value, strings are
+// indices into a per-stream string table, and the result is gzip-compressed.
+package eventstream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"crapp-go/internal/models"
+)
+
+// Version identifies the encoding written by Encode. Each version appends columns
+// after those of the previous one, so Decode reads every earlier version too:
+// version 2 added pointer details and touch gestures, version 3 key categories
+// and press IDs, version 4 page events, version 5 viewport sizes on page events.
+const Version = 5
+
+const (
+	timeScale     = 1000 // Timestamps are kept to the microsecond
+	coordScale    = 100  // Coordinates are kept to a hundredth of a pixel
+	pressureScale = 1000 // Pressure is kept to a thousandth
+)
+
+// Encode packs the events of one question into the compressed columnar format.
+// Question IDs are not stored; the caller keeps them alongside the encoded data.
+func Encode(data *models.InteractionData) ([]byte, error) {
+	w := &writer{strings: make(map[string]uint64)}
+
+	w.uvarint(uint64(len(data.MouseMovements)))
+	w.fixedColumn(len(data.MouseMovements), timeScale, func(i int) float64 { return data.MouseMovements[i].Timestamp })
+	w.fixedColumn(len(data.MouseMovements), coordScale, func(i int) float64 { return data.MouseMovements[i].X })
+	w.fixedColumn(len(data.MouseMovements), coordScale, func(i int) float64 { return data.MouseMovements[i].Y })
+	w.stringColumn(len(data.MouseMovements), func(i int) string { return data.MouseMovements[i].TargetID })
+
+	w.uvarint(uint64(len(data.MouseInteractions)))
+	w.fixedColumn(len(data.MouseInteractions), timeScale, func(i int) float64 { return data.MouseInteractions[i].Timestamp })
+	w.fixedColumn(len(data.MouseInteractions), coordScale, func(i int) float64 { return data.MouseInteractions[i].ClickX })
+	w.fixedColumn(len(data.MouseInteractions), coordScale, func(i int) float64 { return data.MouseInteractions[i].ClickY })
+	w.fixedColumn(len(data.MouseInteractions), coordScale, func(i int) float64 { return data.MouseInteractions[i].TargetX })
+	w.fixedColumn(len(data.MouseInteractions), coordScale, func(i int) float64 { return data.MouseInteractions[i].TargetY })
+	w.stringColumn(len(data.MouseInteractions), func(i int) string { return data.MouseInteractions[i].TargetID })
+	w.stringColumn(len(data.MouseInteractions), func(i int) string { return data.MouseInteractions[i].TargetType })
+
+	w.uvarint(uint64(len(data.KeyboardEvents)))
+	w.fixedColumn(len(data.KeyboardEvents), timeScale, func(i int) float64 { return data.KeyboardEvents[i].Timestamp })
+	w.stringColumn(len(data.KeyboardEvents), func(i int) string { return data.KeyboardEvents[i].Type })
+	w.stringColumn(len(data.KeyboardEvents), func(i int) string { return data.KeyboardEvents[i].Key })
+	w.boolColumn(len(data.KeyboardEvents), func(i int) bool { return data.KeyboardEvents[i].IsModifier })
+
+	// Version 2: pointer details and touch gestures
+	w.pointerColumns(len(data.MouseMovements), func(i int) models.Pointer { return data.MouseMovements[i].Pointer })
+	w.pointerColumns(len(data.MouseInteractions), func(i int) models.Pointer { return data.MouseInteractions[i].Pointer })
+	w.fixedColumn(len(data.MouseInteractions), coordScale, func(i int) float64 { return data.MouseInteractions[i].TargetWidth })
+	w.fixedColumn(len(data.MouseInteractions), coordScale, func(i int) float64 { return data.MouseInteractions[i].TargetHeight })
+	w.fixedColumn(len(data.MouseInteractions), timeScale, func(i int) float64 { return data.MouseInteractions[i].Duration })
+
+	w.uvarint(uint64(len(data.TouchGestures)))
+	w.fixedColumn(len(data.TouchGestures), timeScale, func(i int) float64 { return data.TouchGestures[i].Timestamp })
+	w.fixedColumn(len(data.TouchGestures), timeScale, func(i int) float64 { return data.TouchGestures[i].Duration })
+	w.stringColumn(len(data.TouchGestures), func(i int) string { return data.TouchGestures[i].Type })
+	w.stringColumn(len(data.TouchGestures), func(i int) string { return data.TouchGestures[i].TargetID })
+	w.fixedColumn(len(data.TouchGestures), coordScale, func(i int) float64 { return data.TouchGestures[i].StartX })
+	w.fixedColumn(len(data.TouchGestures), coordScale, func(i int) float64 { return data.TouchGestures[i].StartY })
+	w.fixedColumn(len(data.TouchGestures), coordScale, func(i int) float64 { return data.TouchGestures[i].EndX })
+	w.fixedColumn(len(data.TouchGestures), coordScale, func(i int) float64 { return data.TouchGestures[i].EndY })
+	w.fixedColumn(len(data.TouchGestures), 1, func(i int) float64 { return float64(data.TouchGestures[i].PointerCount) })
+	w.pointerColumns(len(data.TouchGestures), func(i int) models.Pointer { return data.TouchGestures[i].Pointer })
+
+	// Version 3: key categories and press IDs
+	w.stringColumn(len(data.KeyboardEvents), func(i int) string { return data.KeyboardEvents[i].Category })
+	w.fixedColumn(len(data.KeyboardEvents), 1, func(i int) float64 { return float64(data.KeyboardEvents[i].PressID) })
+
+	// Version 4: page events
+	w.uvarint(uint64(len(data.PageEvents)))
+	w.fixedColumn(len(data.PageEvents), timeScale, func(i int) float64 { return data.PageEvents[i].Timestamp })
+	w.stringColumn(len(data.PageEvents), func(i int) string { return data.PageEvents[i].Type })
+	w.stringColumn(len(data.PageEvents), func(i int) string { return data.PageEvents[i].TargetID })
+	w.fixedColumn(len(data.PageEvents), timeScale, func(i int) float64 { return data.PageEvents[i].Value })
+
+	// Version 5: viewport sizes on page events
+	w.fixedColumn(len(data.PageEvents), coordScale, func(i int) float64 { return data.PageEvents[i].Width })
+	w.fixedColumn(len(data.PageEvents), coordScale, func(i int) float64 { return data.PageEvents[i].Height })
+
+	// The string table goes first so the decoder can resolve indices as it reads.
+	var out bytes.Buffer
+	out.WriteByte(Version)
+	zw := gzip.NewWriter(&out)
+	var header []byte
+	header = binary.AppendUvarint(header, uint64(len(w.table)))
+	for _, s := range w.table {
+		header = binary.AppendUvarint(header, uint64(len(s)))
+		header = append(header, s...)
+	}
+	if _, err := zw.Write(header); err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(w.buf); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// Decode unpacks data written by Encode. Every event is tagged with questionID.
+func Decode(encoded []byte, questionID string) (*models.InteractionData, error) {
+	if len(encoded) == 0 {
+		return nil, errors.New("eventstream: empty input")
+	}
+	version := encoded[0]
+	if version < 1 || version > Version {
+		return nil, fmt.Errorf("eventstream: unsupported encoding version %d", encoded[0])
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(encoded[1:]))
+	if err != nil {
+		return nil, fmt.Errorf("eventstream: %w", err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("eventstream: %w", err)
+	}
+
+	r := &reader{buf: raw}
+	tableLen := r.uvarint()
+	for i := uint64(0); i < tableLen && r.err == nil; i++ {
+		r.table = append(r.table, r.string())
+	}
+
+	data := &models.InteractionData{}
+
+	n := r.count()
+	data.MouseMovements = make([]models.MouseMovement, n)
+	r.fixedColumn(n, timeScale, func(i int, v float64) { data.MouseMovements[i].Timestamp = v })
+	r.fixedColumn(n, coordScale, func(i int, v float64) { data.MouseMovements[i].X = v })
+	r.fixedColumn(n, coordScale, func(i int, v float64) { data.MouseMovements[i].Y = v })
+	r.stringColumn(n, func(i int, v string) { data.MouseMovements[i].TargetID = v })
+	for i := range data.MouseMovements {
+		data.MouseMovements[i].QuestionID = questionID
+	}
+
+	n = r.count()
+	data.MouseInteractions = make([]models.MouseInteraction, n)
+	r.fixedColumn(n, timeScale, func(i int, v float64) { data.MouseInteractions[i].Timestamp = v })
+	r.fixedColumn(n, coordScale, func(i int, v float64) { data.MouseInteractions[i].ClickX = v })
+	r.fixedColumn(n, coordScale, func(i int, v float64) { data.MouseInteractions[i].ClickY = v })
+	r.fixedColumn(n, coordScale, func(i int, v float64) { data.MouseInteractions[i].TargetX = v })
+	r.fixedColumn(n, coordScale, func(i int, v float64) { data.MouseInteractions[i].TargetY = v })
+	r.stringColumn(n, func(i int, v string) { data.MouseInteractions[i].TargetID = v })
+	r.stringColumn(n, func(i int, v string) { data.MouseInteractions[i].TargetType = v })
+	for i := range data.MouseInteractions {
+		data.MouseInteractions[i].QuestionID = questionID
+	}
+
+	n = r.count()
+	data.KeyboardEvents = make([]models.KeyboardEvent, n)
+	r.fixedColumn(n, timeScale, func(i int, v float64) { data.KeyboardEvents[i].Timestamp = v })
+	r.stringColumn(n, func(i int, v string) { data.KeyboardEvents[i].Type = v })
+	r.stringColumn(n, func(i int, v string) { data.KeyboardEvents[i].Key = v })
+	r.boolColumn(n, func(i int, v bool) { data.KeyboardEvents[i].IsModifier = v })
+	for i := range data.KeyboardEvents {
+		data.KeyboardEvents[i].QuestionID = questionID
+	}
+
+	if version >= 2 {
+		r.pointerColumns(len(data.MouseMovements), func(i int) *models.Pointer { return &data.MouseMovements[i].Pointer })
+		r.pointerColumns(len(data.MouseInteractions), func(i int) *models.Pointer { return &data.MouseInteractions[i].Pointer })
+		r.fixedColumn(len(data.MouseInteractions), coordScale, func(i int, v float64) { data.MouseInteractions[i].TargetWidth = v })
+		r.fixedColumn(len(data.MouseInteractions), coordScale, func(i int, v float64) { data.MouseInteractions[i].TargetHeight = v })
+		r.fixedColumn(len(data.MouseInteractions), timeScale, func(i int, v float64) { data.MouseInteractions[i].Duration = v })
+
+		n = r.count()
+		data.TouchGestures = make([]models.TouchGesture, n)
+		r.fixedColumn(n, timeScale, func(i int, v float64) { data.TouchGestures[i].Timestamp = v })
+		r.fixedColumn(n, timeScale, func(i int, v float64) { data.TouchGestures[i].Duration = v })
+		r.stringColumn(n, func(i int, v string) { data.TouchGestures[i].Type = v })
+		r.stringColumn(n, func(i int, v string) { data.TouchGestures[i].TargetID = v })
+		r.fixedColumn(n, coordScale, func(i int, v float64) { data.TouchGestures[i].StartX = v })
+		r.fixedColumn(n, coordScale, func(i int, v float64) { data.TouchGestures[i].StartY = v })
+		r.fixedColumn(n, coordScale, func(i int, v float64) { data.TouchGestures[i].EndX = v })
+		r.fixedColumn(n, coordScale, func(i int, v float64) { data.TouchGestures[i].EndY = v })
+		r.fixedColumn(n, 1, func(i int, v float64) { data.TouchGestures[i].PointerCount = int(v) })
+		r.pointerColumns(n, func(i int) *models.Pointer { return &data.TouchGestures[i].Pointer })
+		for i := range data.TouchGestures {
+			data.TouchGestures[i].QuestionID = questionID
+		}
+	}
+
+	if version >= 3 {
+		r.stringColumn(len(data.KeyboardEvents), func(i int, v string) { data.KeyboardEvents[i].Category = v })
+		r.fixedColumn(len(data.KeyboardEvents), 1, func(i int, v float64) { data.KeyboardEvents[i].PressID = int(v) })
+	}
+
+	if version >= 4 {
+		n = r.count()
+		data.PageEvents = make([]models.PageEvent, n)
+		r.fixedColumn(n, timeScale, func(i int, v float64) { data.PageEvents[i].Timestamp = v })
+		r.stringColumn(n, func(i int, v string) { data.PageEvents[i].Type = v })
+		r.stringColumn(n, func(i int, v string) { data.PageEvents[i].TargetID = v })
+		r.fixedColumn(n, timeScale, func(i int, v float64) { data.PageEvents[i].Value = v })
+		for i := range data.PageEvents {
+			data.PageEvents[i].QuestionID = questionID
+		}
+	}
+
+	if version >= 5 {
+		r.fixedColumn(len(data.PageEvents), coordScale, func(i int, v float64) { data.PageEvents[i].Width = v })
+		r.fixedColumn(len(data.PageEvents), coordScale, func(i int, v float64) { data.PageEvents[i].Height = v })
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return data, nil
+}
+
+// writer accumulates encoded columns and the string table they refer to.
+type writer struct {
+	buf     []byte
+	table   []string
+	strings map[string]uint64
+}
+
+func (w *writer) uvarint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *writer) fixedColumn(n int, scale float64, value func(int) float64) {
+	var prev int64
+	for i := 0; i < n; i++ {
+		v := int64(math.Round(value(i) * scale))
+		w.buf = binary.AppendVarint(w.buf, v-prev)
+		prev = v
+	}
+}
+
+func (w *writer) stringColumn(n int, value func(int) string) {
+	for i := 0; i < n; i++ {
+		s := value(i)
+		idx, ok := w.strings[s]
+		if !ok {
+			idx = uint64(len(w.table))
+			w.strings[s] = idx
+			w.table = append(w.table, s)
+		}
+		w.uvarint(idx)
+	}
+}
+
+func (w *writer) pointerColumns(n int, value func(int) models.Pointer) {
+	w.stringColumn(n, func(i int) string { return value(i).PointerType })
+	w.fixedColumn(n, pressureScale, func(i int) float64 { return value(i).Pressure })
+	w.fixedColumn(n, coordScale, func(i int) float64 { return value(i).ContactWidth })
+	w.fixedColumn(n, coordScale, func(i int) float64 { return value(i).ContactHeight })
+}
+
+func (w *writer) boolColumn(n int, value func(int) bool) {
+	for i := 0; i < n; i++ {
+		if value(i) {
+			w.buf = append(w.buf, 1)
+		} else {
+			w.buf = append(w.buf, 0)
+		}
+	}
+}
+
+// reader walks a decompressed stream. The first error sticks and stops all reads.
+type reader struct {
+	buf   []byte
+	table []string
+	err   error
+}
+
+var errTruncated = errors.New("eventstream: truncated input")
+
+func (r *reader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *reader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errTruncated
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+// count reads an event count, bounded by the remaining input so corrupt data
+// can't trigger a huge allocation.
+func (r *reader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		if r.err == nil {
+			r.err = errTruncated
+		}
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) string() string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > uint64(len(r.buf)) {
+		r.err = errTruncated
+		return ""
+	}
+	s := string(r.buf[:n])
+	r.buf = r.buf[n:]
+	return s
+}
+
+func (r *reader) fixedColumn(n int, scale float64, set func(int, float64)) {
+	var prev int64
+	for i := 0; i < n && r.err == nil; i++ {
+		prev += r.varint()
+		set(i, float64(prev)/scale)
+	}
+}
+
+func (r *reader) stringColumn(n int, set func(int, string)) {
+	for i := 0; i < n && r.err == nil; i++ {
+		idx := r.uvarint()
+		if idx >= uint64(len(r.table)) {
+			r.err = fmt.Errorf("eventstream: string index %d out of range", idx)
+			return
+		}
+		set(i, r.table[idx])
+	}
+}
+
+func (r *reader) pointerColumns(n int, pointer func(int) *models.Pointer) {
+	r.stringColumn(n, func(i int, v string) { pointer(i).PointerType = v })
+	r.fixedColumn(n, pressureScale, func(i int, v float64) { pointer(i).Pressure = v })
+	r.fixedColumn(n, coordScale, func(i int, v float64) { pointer(i).ContactWidth = v })
+	r.fixedColumn(n, coordScale, func(i int, v float64) { pointer(i).ContactHeight = v })
+}
+
+func (r *reader) boolColumn(n int, set func(int, bool)) {
+	for i := 0; i < n && r.err == nil; i++ {
+		if len(r.buf) == 0 {
+			r.err = errTruncated
+			return
+		}
+		set(i, r.buf[0] != 0)
+		r.buf = r.buf[1:]
+	}
+}
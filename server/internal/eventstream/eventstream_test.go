@@ -0,0 +1,262 @@
+package eventstream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"crapp-go/internal/models"
+)
+
+// sample has events of every kind, with values at the precision the format keeps.
+func sample() *models.InteractionData {
+	return &models.InteractionData{
+		MouseMovements: []models.MouseMovement{
+			{X: 10.25, Y: 20.5, Timestamp: 100.125, TargetID: "option-1"},
+			{X: 12, Y: 18.75, Timestamp: 116.5, TargetID: "option-1", Pointer: models.Pointer{PointerType: models.PointerPen, Pressure: 0.5}},
+			{X: 300.01, Y: 5, Timestamp: 1200},
+		},
+		MouseInteractions: []models.MouseInteraction{
+			{TargetID: "option-1", TargetType: "radio", ClickX: 12, ClickY: 18.75, TargetX: 14, TargetY: 20, Timestamp: 1300.5,
+				TargetWidth: 120, TargetHeight: 32.5, Duration: 85.25, Pointer: models.Pointer{PointerType: models.PointerMouse}},
+		},
+		KeyboardEvents: []models.KeyboardEvent{
+			{Type: "keydown", Category: models.KeyCategoryCharacter, PressID: 1, Timestamp: 2000},
+			{Type: "keyup", Category: models.KeyCategoryCharacter, PressID: 1, Timestamp: 2080.25},
+		},
+		TouchGestures: []models.TouchGesture{
+			{Type: models.GestureTap, TargetID: "option-2", StartX: 50, StartY: 60, EndX: 51, EndY: 61.5, Timestamp: 1500, Duration: 90, PointerCount: 1,
+				Pointer: models.Pointer{PointerType: models.PointerTouch, Pressure: 0.75, ContactWidth: 11.5, ContactHeight: 12}},
+		},
+		PageEvents: []models.PageEvent{
+			{Type: models.PageQuestionShown, Timestamp: 0, Width: 1280, Height: 720},
+			{Type: models.PageOptionSelected, TargetID: "option-1", Timestamp: 1300.5},
+			{Type: models.PageHidden, Timestamp: 2500, Value: 1200},
+		},
+	}
+}
+
+// tag sets the question ID Decode gives every event.
+func tag(data *models.InteractionData, questionID string) *models.InteractionData {
+	for i := range data.MouseMovements {
+		data.MouseMovements[i].QuestionID = questionID
+	}
+	for i := range data.MouseInteractions {
+		data.MouseInteractions[i].QuestionID = questionID
+	}
+	for i := range data.KeyboardEvents {
+		data.KeyboardEvents[i].QuestionID = questionID
+	}
+	for i := range data.TouchGestures {
+		data.TouchGestures[i].QuestionID = questionID
+	}
+	for i := range data.PageEvents {
+		data.PageEvents[i].QuestionID = questionID
+	}
+	return data
+}
+
+func TestRoundTrip(t *testing.T) {
+	encoded, err := Encode(sample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded[0] != Version {
+		t.Errorf("version byte = %d, want %d", encoded[0], Version)
+	}
+	got, err := Decode(encoded, "q1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := tag(sample(), "q1"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode(Encode(x)) =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestRoundTripRounding(t *testing.T) {
+	// Timestamps keep microseconds, coordinates hundredths of a pixel and
+	// pressure thousandths; anything finer is rounded away.
+	data := &models.InteractionData{
+		MouseMovements: []models.MouseMovement{
+			{X: 10.123, Y: -4.567, Timestamp: 99.99949, Pointer: models.Pointer{Pressure: 0.12345}},
+		},
+	}
+	encoded, err := Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(encoded, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := got.MouseMovements[0]
+	for _, tt := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"x", m.X, 10.12},
+		{"y", m.Y, -4.57},
+		{"timestamp", m.Timestamp, 99.999},
+		{"pressure", m.Pressure, 0.123},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestRoundTripEmpty(t *testing.T) {
+	encoded, err := Encode(&models.InteractionData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(encoded, "q1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(got.MouseMovements) + len(got.MouseInteractions) + len(got.KeyboardEvents) + len(got.TouchGestures) + len(got.PageEvents); n != 0 {
+		t.Errorf("decoded %d events from an empty stream", n)
+	}
+}
+
+// The fixtures were written by the encoder of each earlier version from the same
+// events as sample, less the fields that version did not store.
+func TestDecodeEarlierVersions(t *testing.T) {
+	for version := 1; version < Version; version++ {
+		name := "v" + strconv.Itoa(version)
+		t.Run(name, func(t *testing.T) {
+			encoded, err := os.ReadFile(filepath.Join("testdata", name+".bin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if int(encoded[0]) != version {
+				t.Fatalf("fixture has version %d", encoded[0])
+			}
+			got, err := Decode(encoded, "q1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := tag(asVersion(sample(), version), "q1"); !reflect.DeepEqual(got, want) {
+				t.Errorf("Decode =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
+
+// asVersion clears what the given encoding version did not store.
+func asVersion(data *models.InteractionData, version int) *models.InteractionData {
+	if version < 2 {
+		for i := range data.MouseMovements {
+			data.MouseMovements[i].Pointer = models.Pointer{}
+		}
+		for i := range data.MouseInteractions {
+			data.MouseInteractions[i].Pointer = models.Pointer{}
+			data.MouseInteractions[i].TargetWidth = 0
+			data.MouseInteractions[i].TargetHeight = 0
+			data.MouseInteractions[i].Duration = 0
+		}
+		data.TouchGestures = nil
+	}
+	if version < 3 {
+		for i := range data.KeyboardEvents {
+			data.KeyboardEvents[i].Category = ""
+			data.KeyboardEvents[i].PressID = 0
+		}
+	}
+	if version < 4 {
+		data.PageEvents = nil
+	}
+	if version < 5 {
+		for i := range data.PageEvents {
+			data.PageEvents[i].Width = 0
+			data.PageEvents[i].Height = 0
+		}
+	}
+	return data
+}
+
+func TestDecodeRejectsBadInput(t *testing.T) {
+	encoded, err := Encode(sample())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]byte{
+		"empty":               {},
+		"version zero":        append([]byte{0}, encoded[1:]...),
+		"future version":      append([]byte{Version + 1}, encoded[1:]...),
+		"not gzip":            {Version, 'n', 'o', 't', ' ', 'g', 'z', 'i', 'p'},
+		"gzip header only":    encoded[:11],
+		"missing gzip footer": encoded[:len(encoded)-4],
+	}
+	// Every cut of the stream must fail cleanly rather than panic.
+	for cut := 1; cut < len(encoded); cut++ {
+		tests["truncated at "+strconv.Itoa(cut)] = encoded[:cut]
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(input, "q1"); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsTruncatedColumns(t *testing.T) {
+	encoded, err := Encode(sample())
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(encoded[1:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every cut of the columns, recompressed so gzip itself is intact.
+	for cut := 0; cut < len(raw); cut++ {
+		if _, err := Decode(compress(t, raw[:cut]), "q1"); err == nil {
+			t.Errorf("columns cut at %d of %d: want an error", cut, len(raw))
+		}
+	}
+}
+
+func TestDecodeRejectsCorruptColumns(t *testing.T) {
+	// Well-formed gzip around column data that does not add up.
+	tests := map[string][]byte{
+		"no string table":           {},
+		"string past the end":       {1, 10, 'a'},
+		"count past the end":        {0, 200, 1},
+		"string index out of range": {0, 1, 0, 0, 0, 5},
+		"unterminated varint":       {0, 1, 0xff},
+	}
+	for name, raw := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decode(compress(t, raw), "q1"); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
+
+// compress wraps raw column data in the current version byte and gzip.
+func compress(t *testing.T, raw []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	out.WriteByte(Version)
+	zw := gzip.NewWriter(&out)
+	if _, err := zw.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
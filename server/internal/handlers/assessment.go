@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
-	"crapp-go/internal/metrics"
+	"crapp-go/internal/cognitive"
 	"crapp-go/internal/models"
 	"crapp-go/internal/repository"
+	"crapp-go/internal/services"
 	"crapp-go/views"
 
 	"github.com/a-h/templ"
@@ -16,6 +20,10 @@ import (
 	"go.uber.org/zap"
 )
 
+// reliableChangeTimeout bounds how long completing an assessment waits for the
+// user's change events to be recomputed.
+const reliableChangeTimeout = 30 * time.Second
+
 type AssessmentHandler struct {
 	log        *zap.Logger
 	Assessment *models.Assessment
@@ -51,7 +59,7 @@ func (h *AssessmentHandler) Start(c *gin.Context, isHTMX bool) {
 	currentQuestion := h.Assessment.Questions[state.QuestionOrder[state.CurrentQuestionIndex]]
 
 	// Prepare settings JSON in the handler.
-	settingsJSON := h.prepareSettingsJSON(currentQuestion)
+	settingsJSON := h.prepareSettingsJSON(c, userID, currentQuestion)
 
 	csrfToken, exists := c.Get("csrf_token")
 	if !exists {
@@ -64,7 +72,7 @@ func (h *AssessmentHandler) Start(c *gin.Context, isHTMX bool) {
 		return
 	}
 
-	component := views.AssessmentPage(currentQuestion, state.CurrentQuestionIndex, len(state.QuestionOrder), "", settingsJSON, csrfToken.(string), cspNonce.(string))
+	component := views.AssessmentPage(state.ID, currentQuestion, state.CurrentQuestionIndex, len(state.QuestionOrder), "", settingsJSON, csrfToken.(string), cspNonce.(string))
 
 	if isHTMX {
 		component.Render(c.Request.Context(), c.Writer)
@@ -93,47 +101,13 @@ func (h *AssessmentHandler) NextQuestion(c *gin.Context) {
 	questionID := c.PostForm("questionId")
 	answer := c.PostForm("answer")
 
-	switch currentQuestion.Type {
-	case "cpt":
+	if test, ok := cognitive.Get(currentQuestion.Type); ok {
 		if answer != "" {
-			var data metrics.CPTData
-			if err := json.Unmarshal([]byte(answer), &data); err == nil {
-				summary, events := processCPTData(&data, state.ID)
-				if err := repository.SaveCPTResultTx(summary, events); err != nil {
-					h.log.Error("Failed to save CPT transaction", zap.Error(err), zap.Int("assessmentID", state.ID))
-				}
-			} else {
-				h.log.Error("Failed to unmarshal CPT data", zap.Error(err))
+			if err := h.processCognitiveTest(test, state.ID, answer); err != nil {
+				h.log.Error("Failed to process cognitive test", zap.Error(err), zap.String("testType", test.Type()), zap.Int("assessmentID", state.ID))
 			}
 		}
-
-	case "dst":
-		if answer != "" {
-			var data metrics.DigitSpanRawData
-			if err := json.Unmarshal([]byte(answer), &data); err == nil {
-				summary := processDSTData(&data, state.ID)
-				if err := repository.SaveDSTResultTx(summary, data.Results); err != nil {
-					h.log.Error("Failed to save DST transaction", zap.Error(err), zap.Int("assessmentID", state.ID))
-				}
-			} else {
-				h.log.Error("Failed to unmarshal DST data", zap.Error(err))
-			}
-		}
-
-	case "tmt":
-		if answer != "" {
-			var data metrics.TrailMakingData
-			if err := json.Unmarshal([]byte(answer), &data); err == nil {
-				summary := processTMTData(&data, state.ID)
-				if err := repository.SaveTMTResultTx(summary, data.Clicks); err != nil {
-					h.log.Error("Failed to save TMT transaction", zap.Error(err), zap.Int("assessmentID", state.ID))
-				}
-			} else {
-				h.log.Error("Failed to unmarshal TMT data", zap.Error(err))
-			}
-		}
-
-	default:
+	} else {
 		if currentQuestion.Required && answer == "" {
 			errorMessage := "This question is required. Please select an answer."
 
@@ -149,7 +123,7 @@ func (h *AssessmentHandler) NextQuestion(c *gin.Context) {
 				return
 			}
 			// Re-render the same page with an error message AND the CSRF token
-			views.AssessmentPage(currentQuestion, state.CurrentQuestionIndex, len(state.QuestionOrder), errorMessage, "", csrfToken.(string), cspNonce.(string)).Render(c, c.Writer)
+			views.AssessmentPage(state.ID, currentQuestion, state.CurrentQuestionIndex, len(state.QuestionOrder), errorMessage, "", csrfToken.(string), cspNonce.(string)).Render(c, c.Writer)
 			return // Stop processing
 		}
 		if err := repository.SaveAnswer(uint(state.ID), questionID, answer); err != nil {
@@ -165,11 +139,21 @@ func (h *AssessmentHandler) NextQuestion(c *gin.Context) {
 
 	if nextIndex >= len(state.QuestionOrder) {
 		repository.CompleteAssessment(uint(state.ID))
+		if err := services.FinalizeInteractionMetrics(c, h.Assessment, uint(state.ID)); err != nil {
+			h.log.Error("Failed to finalize interaction metrics", zap.Error(err), zap.Int("assessmentID", state.ID))
+		}
+		// A failure leaves the previous change events in place; the next completed
+		// assessment recomputes all of them.
+		ctx, cancel := context.WithTimeout(c, reliableChangeTimeout)
+		if err := services.RecordReliableChanges(ctx, userID, h.Assessment); err != nil {
+			h.log.Error("Failed to record reliable changes", zap.Error(err), zap.Int("userID", userID))
+		}
+		cancel()
 		c.Header("HX-Redirect", "/assessment/results")
 		c.AbortWithStatus(http.StatusOK)
 	} else {
 		nextQuestion := h.Assessment.Questions[state.QuestionOrder[nextIndex]]
-		settingsJSON := h.prepareSettingsJSON(nextQuestion)
+		settingsJSON := h.prepareSettingsJSON(c, userID, nextQuestion)
 		csrfToken, exists := c.Get("csrf_token")
 		if !exists {
 			c.AbortWithStatus(http.StatusInternalServerError)
@@ -181,7 +165,7 @@ func (h *AssessmentHandler) NextQuestion(c *gin.Context) {
 			return
 		}
 
-		views.AssessmentPage(nextQuestion, nextIndex, len(state.QuestionOrder), "", settingsJSON, csrfToken.(string), cspNonce.(string)).Render(c, c.Writer)
+		views.AssessmentPage(state.ID, nextQuestion, nextIndex, len(state.QuestionOrder), "", settingsJSON, csrfToken.(string), cspNonce.(string)).Render(c, c.Writer)
 	}
 }
 
@@ -213,7 +197,7 @@ func (h *AssessmentHandler) PreviousQuestion(c *gin.Context) {
 	}
 
 	prevQuestion := h.Assessment.Questions[state.QuestionOrder[prevIndex]]
-	settingsJSON := h.prepareSettingsJSON(prevQuestion)
+	settingsJSON := h.prepareSettingsJSON(c, userID, prevQuestion)
 	csrfToken, exists := c.Get("csrf_token")
 	if !exists {
 		c.AbortWithStatus(http.StatusInternalServerError)
@@ -224,17 +208,20 @@ func (h *AssessmentHandler) PreviousQuestion(c *gin.Context) {
 		c.AbortWithStatus(http.StatusInternalServerError)
 		return
 	}
-	views.AssessmentPage(prevQuestion, prevIndex, len(state.QuestionOrder), "", settingsJSON, csrfToken.(string), cspNonce.(string)).Render(c, c.Writer)
+	views.AssessmentPage(state.ID, prevQuestion, prevIndex, len(state.QuestionOrder), "", settingsJSON, csrfToken.(string), cspNonce.(string)).Render(c, c.Writer)
 }
 
-func (h *AssessmentHandler) prepareSettingsJSON(question models.Question) string {
-	if question.Type != "cpt" && question.Type != "dst" && question.Type != "tmt" {
+func (h *AssessmentHandler) prepareSettingsJSON(c *gin.Context, userID int, question models.Question) string {
+	test, ok := cognitive.Get(question.Type)
+	if !ok {
 		return "{}" // No settings needed for standard questions, but return valid JSON
 	}
 	settings := make(map[string]interface{})
 	for _, option := range question.Options {
 		settings[option.Label] = option.Value
 	}
+	settings["practiceEnabled"] = h.practiceRequired(c, userID, test, settings)
+
 	settingsJSON, err := json.Marshal(settings)
 	if err != nil {
 		h.log.Error("Failed to marshal question settings", zap.Error(err), zap.String("questionId", question.ID))
@@ -243,65 +230,34 @@ func (h *AssessmentHandler) prepareSettingsJSON(question models.Question) string
 	return string(settingsJSON)
 }
 
-// --- Data Processing Helpers ---
-
-func processCPTData(data *metrics.CPTData, assessmentID int) (models.CPTResult, []models.CPTEvent) {
-	summary := models.CPTResult{
-		AssessmentID:        uint(assessmentID),
-		CorrectDetections:   metrics.CountCorrectDetections(data),
-		CommissionErrors:    metrics.CountCommissionErrors(data),
-		OmissionErrors:      metrics.CountOmissionErrors(data),
-		AverageReactionTime: metrics.CalculateAverageReactionTime(data),
-		ReactionTimeSD:      metrics.CalculateReactionTimeSD(data),
-		DetectionRate:       metrics.CalculateDetectionRate(data),
-		OmissionErrorRate:   metrics.CalculateOmissionErrorRate(data),
-		CommissionErrorRate: metrics.CalculateCommissionErrorRate(data),
-	}
-
-	var events []models.CPTEvent
-	for _, stim := range data.StimuliPresented {
-		s := stim // Local copy for pointer safety
-		events = append(events, models.CPTEvent{
-			EventType:     "stimulus",
-			StimulusValue: &s.Value,
-			IsTarget:      &s.IsTarget,
-			PresentedAt:   &s.PresentedAt,
-		})
+// practiceRequired decides whether the practice block should run before a test.
+// Practice runs when practiceTrials is set, and, if practiceSessions is set, only
+// for the user's first practiceSessions administrations of the test.
+func (h *AssessmentHandler) practiceRequired(c *gin.Context, userID int, test cognitive.CognitiveTest, settings map[string]interface{}) bool {
+	trials, _ := strconv.Atoi(fmt.Sprint(settings["practiceTrials"]))
+	if trials <= 0 {
+		return false
 	}
-	for _, resp := range data.Responses {
-		r := resp // Local copy for pointer safety
-		events = append(events, models.CPTEvent{
-			EventType:     "response",
-			StimulusValue: &r.Stimulus,
-			IsTarget:      &r.IsTarget,
-			ResponseTime:  &r.ResponseTime,
-			StimulusIndex: &r.StimulusIndex,
-		})
+	runs, _ := strconv.Atoi(fmt.Sprint(settings["practiceSessions"]))
+	if runs <= 0 {
+		return true // Practice on every administration
 	}
 
-	return summary, events
-}
-
-func processDSTData(data *metrics.DigitSpanRawData, assessmentID int) models.DSTResult {
-	processedResult, _ := metrics.CalculateDigitSpanMetrics(data)
-	return models.DSTResult{
-		AssessmentID:        uint(assessmentID),
-		HighestSpanAchieved: processedResult.HighestSpanAchieved,
-		TotalTrials:         processedResult.TotalTrials,
-		CorrectTrials:       processedResult.CorrectTrials,
-		CreatedAt:           time.Now(),
+	completed, err := repository.CountCognitiveRuns(c, uint(userID), test)
+	if err != nil {
+		h.log.Error("Failed to count previous test runs", zap.Error(err), zap.String("testType", test.Type()), zap.Int("userID", userID))
+		return true // Err on the side of giving the user practice
 	}
+	return completed < int64(runs)
 }
 
-func processTMTData(data *metrics.TrailMakingData, assessmentID int) models.TMTResult {
-	processedResult := metrics.CalculateTrailMetrics(data)
-	return models.TMTResult{
-		AssessmentID:        uint(assessmentID),
-		PartACompletionTime: processedResult.PartACompletionTime,
-		PartAErrors:         processedResult.PartAErrors,
-		PartBCompletionTime: processedResult.PartBCompletionTime,
-		PartBErrors:         processedResult.PartBErrors,
-		BToARatio:           processedResult.BToARatio,
-		CreatedAt:           time.Now(),
+// --- Data Processing Helpers ---
+
+// processCognitiveTest scores a cognitive test payload and saves it with the raw data.
+func (h *AssessmentHandler) processCognitiveTest(test cognitive.CognitiveTest, assessmentID int, answer string) error {
+	submission, err := cognitive.Process(test, uint(assessmentID), []byte(answer))
+	if err != nil {
+		return err
 	}
+	return repository.SaveCognitiveSubmission(submission)
 }
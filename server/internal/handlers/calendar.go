@@ -0,0 +1,160 @@
+// server/internal/handlers/calendar.go
+package handlers
+
+import (
+	"crapp-go/internal/models"
+	"crapp-go/internal/repository"
+	"crapp-go/views"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/go-echarts/go-echarts/v2/types"
+)
+
+// calendarComposite colors the calendar by the mean of every symptom score.
+const calendarComposite = "composite"
+
+// ShowCalendar renders a year of the user's local days, marking the days an
+// assessment was completed and coloring them by a symptom or composite score.
+// Clicking a day opens its assessment.
+func (h *ResultsHandler) ShowCalendar(c *gin.Context) {
+	session := sessions.Default(c)
+	userID, ok := session.Get("userID").(int)
+	if !ok {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	timeZone, loc := userLocation(c)
+
+	// The calendar is colored by one symptom, or by all of them averaged.
+	var symptoms []models.MetricOption
+	var scoreKeys []repository.SeriesKey
+	colorBy := c.DefaultQuery("color", calendarComposite)
+	for _, q := range h.Assessment.Questions {
+		if q.Type != "radio" {
+			continue
+		}
+		symptoms = append(symptoms, models.MetricOption{Value: q.ID, Label: q.Title})
+		if colorBy == calendarComposite || colorBy == q.ID {
+			scoreKeys = append(scoreKeys, repository.SeriesKey{QuestionID: q.ID, MetricKey: q.ID})
+		}
+	}
+	if len(scoreKeys) == 0 {
+		colorBy = calendarComposite
+		for _, symptom := range symptoms {
+			scoreKeys = append(scoreKeys, repository.SeriesKey{QuestionID: symptom.Value, MetricKey: symptom.Value})
+		}
+	}
+
+	today := time.Now().In(loc)
+	from := today.AddDate(-1, 0, 1)
+	days, err := repository.GetCalendarDays(c, userID, timeZone, scoreKeys, from, today)
+	if err != nil {
+		h.log.Error("Failed to get calendar days", zap.Error(err), zap.Int("userID", userID))
+		c.String(http.StatusInternalServerError, "Failed to load calendar")
+		return
+	}
+
+	// The page maps each day back to its assessment when the day is clicked.
+	assessments := make(map[string]string, len(days))
+	for _, day := range days {
+		assessments[day.Day.Format("2006-01-02")] = strconv.FormatUint(uint64(day.AssessmentID), 10)
+	}
+	calendarOptionsJSON, _ := json.Marshal(generateCalendarChart(days, from, today))
+	assessmentsJSON, _ := json.Marshal(assessments)
+
+	views.CalendarHeatmap(symptoms, colorBy, string(calendarOptionsJSON), string(assessmentsJSON), len(days)).Render(c.Request.Context(), c.Writer)
+}
+
+// calendarVisualMap limits the severity colors to the score series, so the
+// completion markers keep their own color. go-echarts does not expose seriesIndex.
+type calendarVisualMap struct {
+	opts.VisualMap
+	SeriesIndex int `json:"seriesIndex"`
+}
+
+// generateCalendarChart colors each scored day by its score and marks every day
+// with a completed assessment. Days without a mark were missed.
+func generateCalendarChart(days []repository.CalendarDay, from, to time.Time) map[string]interface{} {
+	heatmap := charts.NewHeatMap()
+	heatmap.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Assessment Calendar",
+			Subtitle: "Dots mark completed assessments; colors show severity",
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+	)
+	heatmap.AddCalendar(&opts.Calendar{
+		Range:    []string{from.Format("2006-01-02"), to.Format("2006-01-02")},
+		CellSize: "auto",
+		Top:      "90",
+		Left:     "40",
+		Right:    "20",
+		DayLabel: &opts.CalendarLabel{FirstDay: 1},
+	})
+
+	maxScore := 1.0
+	scores := make([]opts.HeatMapData, 0, len(days))
+	completed := make([]opts.ScatterData, 0, len(days))
+	for _, day := range days {
+		date := day.Day.Format("2006-01-02")
+		completed = append(completed, opts.ScatterData{Value: []interface{}{date, day.Assessments}})
+		if day.Score != nil {
+			scores = append(scores, opts.HeatMapData{Value: []interface{}{date, *day.Score}})
+			maxScore = max(maxScore, *day.Score)
+		}
+	}
+	heatmap.AddSeries("Severity", scores, charts.WithCoordinateSystem("calendar"))
+	heatmap.MultiSeries = append(heatmap.MultiSeries, charts.SingleSeries{
+		Name:        "Completed",
+		Type:        types.ChartScatter,
+		CoordSystem: "calendar",
+		Data:        completed,
+		SymbolSize:  6,
+		ItemStyle:   &opts.ItemStyle{Color: "#1f2937"},
+	})
+
+	options := heatmap.JSON()
+	options["visualMap"] = []calendarVisualMap{{
+		VisualMap: opts.VisualMap{
+			Calculable: opts.Bool(true),
+			Min:        0,
+			Max:        float32(maxScore),
+			Orient:     "horizontal",
+			Left:       "center",
+			Top:        "40",
+			InRange:    &opts.VisualMapInRange{Color: []string{"#fef9c3", "#f97316", "#b91c1c"}},
+		},
+		SeriesIndex: 0,
+	}}
+	return options
+}
+
+// userLocation returns the signed-in user's time zone, falling back to UTC when
+// it is missing or unknown.
+func userLocation(c *gin.Context) (string, *time.Location) {
+	if user, ok := c.Get("user"); ok {
+		return locationOf(user.(*models.User))
+	}
+	return "UTC", time.UTC
+}
+
+// locationOf returns a user's time zone, falling back to UTC when it is missing
+// or unknown.
+func locationOf(user *models.User) (string, *time.Location) {
+	if user.TimeZone != "" {
+		if loc, err := time.LoadLocation(user.TimeZone); err == nil {
+			return user.TimeZone, loc
+		}
+	}
+	return "UTC", time.UTC
+}
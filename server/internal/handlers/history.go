@@ -0,0 +1,462 @@
+// server/internal/handlers/history.go
+package handlers
+
+import (
+	"context"
+	"crapp-go/internal/cognitive"
+	"crapp-go/internal/config"
+	"crapp-go/internal/models"
+	"crapp-go/internal/repository"
+	"crapp-go/internal/services"
+	"crapp-go/views"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/go-echarts/go-echarts/v2/types"
+)
+
+// HistoryHandler shows a user's past assessments.
+type HistoryHandler struct {
+	log        *zap.Logger
+	Assessment *models.Assessment
+}
+
+func NewHistoryHandler(log *zap.Logger, assessment *models.Assessment) *HistoryHandler {
+	return &HistoryHandler{log: log, Assessment: assessment}
+}
+
+// ListAssessments renders one page of the user's past assessments. Clinicians
+// may list the assessments of a patient who granted them access with the user
+// query parameter.
+func (h *HistoryHandler) ListAssessments(c *gin.Context) {
+	user, ok := c.Get("user")
+	if !ok {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+	viewer := user.(*models.User)
+
+	userID := int(viewer.ID)
+	forUser := c.Query("user")
+	if forUser != "" {
+		id, err := strconv.Atoi(forUser)
+		if err != nil {
+			c.String(http.StatusForbidden, "Not allowed")
+			return
+		}
+		allowed, err := canReview(c, viewer, id)
+		if err != nil {
+			h.log.Error("Failed to check clinician access", zap.Error(err), zap.Uint("viewerID", viewer.ID), zap.Int("userID", id))
+			c.String(http.StatusInternalServerError, "Failed to load history")
+			return
+		}
+		if !allowed {
+			c.String(http.StatusForbidden, "Not allowed")
+			return
+		}
+		userID = id
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize := config.Conf.Results.HistoryPageSize
+	summaries, total, err := repository.ListAssessments(c, userID, page, pageSize)
+	if err != nil {
+		h.log.Error("Failed to list assessments", zap.Error(err), zap.Int("userID", userID))
+		c.String(http.StatusInternalServerError, "Failed to load history")
+		return
+	}
+
+	loc, err := patientLocation(c, viewer, userID)
+	if err != nil {
+		h.log.Error("Failed to get user", zap.Error(err), zap.Int("userID", userID))
+		c.String(http.StatusInternalServerError, "Failed to load history")
+		return
+	}
+	rows := make([]views.HistoryRow, len(summaries))
+	for i, summary := range summaries {
+		rows[i] = views.HistoryRow{
+			ID:                 summary.ID,
+			TakenAt:            summary.CreatedAt.In(loc),
+			Duration:           summary.UpdatedAt.Sub(summary.CreatedAt),
+			IsComplete:         summary.IsComplete,
+			Invalidated:        summary.InvalidatedAt != nil,
+			InvalidationReason: summary.InvalidationReason,
+			FlaggedTests:       summary.FlaggedTests,
+		}
+	}
+	pages := int((total + int64(pageSize) - 1) / int64(pageSize))
+	h.render(c, "History", views.AssessmentHistory(rows, page, pages, forUser))
+}
+
+// InvalidateAssessment marks an assessment invalid with the reason given in the
+// form, then shows it again.
+func (h *HistoryHandler) InvalidateAssessment(c *gin.Context) {
+	state, viewer := h.viewableAssessment(c)
+	if state == nil {
+		return
+	}
+	reason := strings.TrimSpace(c.PostForm("reason"))
+	if reason == "" || len(reason) > 500 {
+		c.String(http.StatusBadRequest, "Please give a reason of at most 500 characters")
+		return
+	}
+	if err := services.InvalidateAssessment(c, state, int(viewer.ID), reason, h.Assessment); err != nil {
+		h.log.Error("Failed to invalidate assessment", zap.Error(err), zap.Int("assessmentID", state.ID))
+		c.String(http.StatusInternalServerError, "Failed to mark the assessment invalid")
+		return
+	}
+	h.log.Info("Assessment marked invalid", zap.Int("assessmentID", state.ID), zap.Uint("by", viewer.ID))
+	h.ShowAssessment(c)
+}
+
+// RestoreAssessment clears an assessment's invalidation, then shows it again.
+func (h *HistoryHandler) RestoreAssessment(c *gin.Context) {
+	state, viewer := h.viewableAssessment(c)
+	if state == nil {
+		return
+	}
+	if err := services.RestoreAssessment(c, state, h.Assessment); err != nil {
+		h.log.Error("Failed to restore assessment", zap.Error(err), zap.Int("assessmentID", state.ID))
+		c.String(http.StatusInternalServerError, "Failed to mark the assessment valid")
+		return
+	}
+	h.log.Info("Assessment marked valid", zap.Int("assessmentID", state.ID), zap.Uint("by", viewer.ID))
+	h.ShowAssessment(c)
+}
+
+// viewableAssessment loads the completed assessment named in the URL, if the
+// signed-in user took it or is a clinician the user who took it granted access,
+// along with the signed-in user. When it cannot, it writes the response itself
+// and returns a nil assessment.
+func (h *HistoryHandler) viewableAssessment(c *gin.Context) (*models.AssessmentState, *models.User) {
+	user, ok := c.Get("user")
+	if !ok {
+		c.Redirect(http.StatusFound, "/")
+		return nil, nil
+	}
+	viewer := user.(*models.User)
+
+	assessmentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid assessment")
+		return nil, nil
+	}
+	state, err := repository.GetAssessmentState(c, uint(assessmentID))
+	if err != nil || !state.IsComplete {
+		c.String(http.StatusNotFound, "Assessment not found")
+		return nil, nil
+	}
+	allowed, err := canReview(c, viewer, state.UserID)
+	if err != nil {
+		h.log.Error("Failed to check clinician access", zap.Error(err), zap.Uint("viewerID", viewer.ID), zap.Int("assessmentID", state.ID))
+		c.String(http.StatusInternalServerError, "Failed to load assessment")
+		return nil, nil
+	}
+	if !allowed {
+		c.String(http.StatusNotFound, "Assessment not found")
+		return nil, nil
+	}
+	return state, viewer
+}
+
+// canReview reports whether the viewer may see and invalidate the user's
+// assessments: their own, or those of a patient who granted them access.
+func canReview(ctx context.Context, viewer *models.User, userID int) (bool, error) {
+	if userID == int(viewer.ID) {
+		return true, nil
+	}
+	return repository.HasClinicianAccess(ctx, viewer.ID, uint(userID))
+}
+
+// patientLocation returns the time zone of the user whose assessments are shown,
+// so a clinician sees the dates and times the patient saw.
+func patientLocation(ctx context.Context, viewer *models.User, userID int) (*time.Location, error) {
+	patient := viewer
+	if userID != int(viewer.ID) {
+		var err error
+		if patient, err = repository.GetUserByID(ctx, uint(userID)); err != nil {
+			return nil, err
+		}
+	}
+	_, loc := locationOf(patient)
+	return loc, nil
+}
+
+// ShowAssessment renders one completed assessment.
+func (h *HistoryHandler) ShowAssessment(c *gin.Context) {
+	state, viewer := h.viewableAssessment(c)
+	if state == nil {
+		return
+	}
+
+	answers, err := repository.GetAnswersForAssessment(uint(state.ID))
+	if err != nil {
+		h.log.Error("Failed to get answers", zap.Error(err), zap.Int("assessmentID", state.ID))
+		c.String(http.StatusInternalServerError, "Failed to load assessment")
+		return
+	}
+
+	values, err := repository.GetAssessmentMetrics(c, state.UserID, uint(state.ID))
+	if err != nil {
+		h.log.Error("Failed to get assessment metrics", zap.Error(err), zap.Int("assessmentID", state.ID))
+		c.String(http.StatusInternalServerError, "Failed to load assessment")
+		return
+	}
+	byQuestion := make(map[string]map[string]float64)
+	for _, v := range values {
+		if byQuestion[v.QuestionID] == nil {
+			byQuestion[v.QuestionID] = make(map[string]float64)
+		}
+		byQuestion[v.QuestionID][v.MetricKey] = v.Value
+	}
+
+	// Questions are shown in assessment order: interaction metrics for the survey
+	// questions, and a summary with a trial plot for each cognitive test.
+	var interaction []views.QuestionMetrics
+	var tests []views.CognitiveSummary
+	for _, q := range h.Assessment.Questions {
+		recorded := byQuestion[q.ID]
+		test, isCognitive := cognitive.Get(q.Type)
+		if !isCognitive {
+			if rows := metricRows(getAvailableMetrics(q), recorded, q.ID); len(rows) > 0 {
+				interaction = append(interaction, views.QuestionMetrics{Title: q.Title, Metrics: rows})
+			}
+			continue
+		}
+
+		validity, err := repository.GetResultValidity(c, test, uint(state.ID))
+		if err != nil {
+			h.log.Error("Failed to get result validity", zap.Error(err), zap.String("testType", test.Type()), zap.Int("assessmentID", state.ID))
+			c.String(http.StatusInternalServerError, "Failed to load assessment")
+			return
+		}
+		if validity == nil {
+			continue // The test was not run in this assessment
+		}
+		chart, err := trialChart(c, test, uint(state.ID))
+		if err != nil {
+			h.log.Error("Failed to get trials", zap.Error(err), zap.String("testType", test.Type()), zap.Int("assessmentID", state.ID))
+			c.String(http.StatusInternalServerError, "Failed to load assessment")
+			return
+		}
+		summary := views.CognitiveSummary{
+			Type:     test.Type(),
+			Title:    test.Title(),
+			Metrics:  metricRows(test.Metrics(), recorded, ""),
+			Validity: *validity,
+		}
+		if chart != nil {
+			chartJSON, _ := json.Marshal(chart.JSON())
+			summary.TrialChart = string(chartJSON)
+		}
+		tests = append(tests, summary)
+	}
+	sessionMetrics := metricRows(nil, byQuestion["global"], "")
+
+	loc, err := patientLocation(c, viewer, state.UserID)
+	if err != nil {
+		h.log.Error("Failed to get user", zap.Error(err), zap.Int("userID", state.UserID))
+		c.String(http.StatusInternalServerError, "Failed to load assessment")
+		return
+	}
+	var invalidation *views.Invalidation
+	if state.IsInvalidated() {
+		invalidation = &views.Invalidation{
+			At:          state.InvalidatedAt.In(loc),
+			ByClinician: state.InvalidatedByID != nil && *state.InvalidatedByID != state.UserID,
+			Reason:      state.InvalidationReason,
+		}
+	}
+	component := views.AssessmentDetail(state.ID, state.CreatedAt.In(loc), invalidation, answerRows(h.Assessment.Questions, answers), interaction, tests, sessionMetrics)
+	h.render(c, "Assessment", component)
+}
+
+// render writes a page, inside the layout unless htmx asked for it.
+func (h *HistoryHandler) render(c *gin.Context, title string, component templ.Component) {
+	if c.GetHeader("HX-Request") == "true" {
+		component.Render(c.Request.Context(), c.Writer)
+	} else {
+		csrfToken, _ := c.Get("csrf_token")
+		cspNonce, _ := c.Get("csp_nonce")
+		views.Layout(title, true, csrfToken.(string), cspNonce.(string)).Render(
+			templ.WithChildren(c.Request.Context(), component),
+			c.Writer,
+		)
+	}
+}
+
+// metricRows lists the recorded values in the order of the known metrics, then
+// any others by key. skip names a key that is not an interaction metric, such as
+// a symptom's own score.
+func metricRows(known []models.MetricOption, recorded map[string]float64, skip string) []views.MetricRow {
+	rows := make([]views.MetricRow, 0, len(recorded))
+	seen := make(map[string]bool, len(known))
+	for _, metric := range known {
+		seen[metric.Value] = true
+		if value, ok := recorded[metric.Value]; ok {
+			rows = append(rows, views.MetricRow{Label: metric.Label, Value: value})
+		}
+	}
+	others := make([]string, 0)
+	for key := range recorded {
+		if !seen[key] && key != skip {
+			others = append(others, key)
+		}
+	}
+	slices.Sort(others)
+	for _, key := range others {
+		rows = append(rows, views.MetricRow{Label: strings.Title(strings.ReplaceAll(key, "_", " ")), Value: recorded[key]})
+	}
+	return rows
+}
+
+// trialChart plots the trials of a cognitive test run, or returns nil for tests
+// without a trial plot.
+func trialChart(c *gin.Context, test cognitive.CognitiveTest, assessmentID uint) (interface{ JSON() map[string]interface{} }, error) {
+	switch test.Type() {
+	case "cpt":
+		events, err := repository.GetCPTEvents(c, assessmentID)
+		if err != nil {
+			return nil, err
+		}
+		return cptTrialChart(events), nil
+	case "dst":
+		attempts, err := repository.GetDSTAttempts(c, assessmentID)
+		if err != nil {
+			return nil, err
+		}
+		return dstTrialChart(attempts), nil
+	case "tmt":
+		clicks, err := repository.GetTMTClicks(c, assessmentID)
+		if err != nil {
+			return nil, err
+		}
+		return tmtTrialChart(clicks), nil
+	}
+	return nil, nil
+}
+
+// cptTrialChart plots the reaction time of every response against the stimulus it
+// answered, separating hits on targets from commission errors.
+func cptTrialChart(events []models.CPTEvent) *charts.Scatter {
+	scatter := charts.NewScatter()
+	scatter.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Responses", Subtitle: "Reaction time per stimulus"}),
+		charts.WithXAxisOpts(opts.XAxis{Type: "value", Name: "Stimulus"}),
+		charts.WithYAxisOpts(opts.YAxis{Type: "value", Name: "ms"}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+	)
+
+	hits := make([]opts.ScatterData, 0)
+	commissions := make([]opts.ScatterData, 0)
+	for _, event := range events {
+		if event.EventType != "response" || event.ResponseTime == nil || event.StimulusIndex == nil {
+			continue
+		}
+		point := opts.ScatterData{Value: []interface{}{*event.StimulusIndex + 1, *event.ResponseTime}}
+		if event.IsTarget != nil && *event.IsTarget {
+			hits = append(hits, point)
+		} else {
+			commissions = append(commissions, point)
+		}
+	}
+	scatter.AddSeries("Hits", hits, charts.WithItemStyleOpts(opts.ItemStyle{Color: "#16a34a"}))
+	scatter.AddSeries("Commission errors", commissions, charts.WithItemStyleOpts(opts.ItemStyle{Color: "#dc2626"}))
+	return scatter
+}
+
+// dstTrialChart plots the span of every trial in order, marking whether it was
+// recalled correctly.
+func dstTrialChart(attempts []models.DSTAttempt) *charts.Scatter {
+	scatter := charts.NewScatter()
+	scatter.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Trials", Subtitle: "Span of each trial"}),
+		charts.WithXAxisOpts(opts.XAxis{Type: "value", Name: "Trial", MinInterval: 1}),
+		charts.WithYAxisOpts(opts.YAxis{Type: "value", Name: "Span", MinInterval: 1}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+	)
+
+	correct := make([]opts.ScatterData, 0)
+	incorrect := make([]opts.ScatterData, 0)
+	for i, attempt := range attempts {
+		point := opts.ScatterData{Value: []interface{}{i + 1, attempt.Span}}
+		if attempt.IsCorrect {
+			correct = append(correct, point)
+		} else {
+			incorrect = append(incorrect, point)
+		}
+	}
+	scatter.AddSeries("Correct", correct, charts.WithItemStyleOpts(opts.ItemStyle{Color: "#16a34a"}))
+	scatter.AddSeries("Incorrect", incorrect, charts.WithItemStyleOpts(opts.ItemStyle{Color: "#dc2626"}))
+	return scatter
+}
+
+// tmtTrialChart draws the path of clicks through each part of the test, in
+// screen coordinates, with clicks that missed every item marked separately.
+func tmtTrialChart(clicks []models.TMTClick) *charts.Line {
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Click Path", Subtitle: "Clicks in screen coordinates"}),
+		charts.WithXAxisOpts(opts.XAxis{Type: "value", Name: "x"}),
+		charts.WithYAxisOpts(opts.YAxis{Type: "value", Name: "y", Inverse: opts.Bool(true)}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+	)
+
+	parts := make(map[string][]opts.LineData)
+	var order []string
+	misses := make([]opts.ScatterData, 0)
+	for _, click := range clicks {
+		if click.TargetItem < 0 {
+			misses = append(misses, opts.ScatterData{Value: []interface{}{click.X, click.Y}})
+			continue
+		}
+		if _, ok := parts[click.CurrentPart]; !ok {
+			order = append(order, click.CurrentPart)
+		}
+		parts[click.CurrentPart] = append(parts[click.CurrentPart], opts.LineData{Value: []interface{}{click.X, click.Y}})
+	}
+	for _, part := range order {
+		line.AddSeries(part, parts[part])
+	}
+	line.MultiSeries = append(line.MultiSeries, charts.SingleSeries{
+		Name:      "Missed clicks",
+		Type:      types.ChartScatter,
+		Data:      misses,
+		ItemStyle: &opts.ItemStyle{Color: "#dc2626"},
+	})
+	return line
+}
+
+// answerRows lists the answers in question order, with the label of the chosen
+// option for questions that have options.
+func answerRows(questions []models.Question, answers map[string]string) []views.AnswerRow {
+	rows := make([]views.AnswerRow, 0, len(answers))
+	for _, q := range questions {
+		value, ok := answers[q.ID]
+		if !ok {
+			continue
+		}
+		row := views.AnswerRow{Question: q.Title, Value: value}
+		for _, option := range q.Options {
+			if option.Value == value {
+				row.Label = option.Label
+				break
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
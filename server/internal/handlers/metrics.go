@@ -2,25 +2,44 @@
 package handlers
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 
+	"crapp-go/internal/config"
 	"crapp-go/internal/metrics"
 	"crapp-go/internal/models"
 	"crapp-go/internal/repository"
+	"crapp-go/internal/services"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type MetricsHandler struct {
-	log *zap.Logger
+	log        *zap.Logger
+	Assessment *models.Assessment
 }
 
-func NewMetricsHandler(log *zap.Logger) *MetricsHandler {
-	return &MetricsHandler{log: log}
+func NewMetricsHandler(log *zap.Logger, assessment *models.Assessment) *MetricsHandler {
+	return &MetricsHandler{log: log, Assessment: assessment}
 }
 
+// SaveMetrics accepts interaction data as NDJSON, one chunk per line. Each chunk
+// names the assessment it was recorded in and carries the client's tracker session
+// ID and a sequence number; chunks that were already received are acknowledged
+// without being stored again, so the client can safely retransmit anything it has
+// not seen acknowledged. Chunks that cannot be attributed to one of the user's
+// assessments are quarantined rather than saved. Metrics are computed from the
+// accumulated chunks once the assessment is complete.
+//
+// Requests larger than the configured body limit, or with a chunk holding more
+// events than allowed, are rejected with 413. Implausible events are dropped from
+// each chunk before it is stored and counted on the chunk.
 func (h *MetricsHandler) SaveMetrics(c *gin.Context) {
 	session := sessions.Default(c)
 	userID, ok := session.Get("userID").(int)
@@ -29,36 +48,205 @@ func (h *MetricsHandler) SaveMetrics(c *gin.Context) {
 		return
 	}
 
-	var interactionData models.InteractionData
-	if err := c.ShouldBindJSON(&interactionData); err != nil {
-		h.log.Error("Failed to bind interaction data", zap.Error(err))
+	limits := config.Conf.Interaction.Limits
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limits.MaxBodyBytes)
+
+	payloads, err := parseInteractionChunks(c, limits.MaxBodyBytes)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || errors.Is(err, bufio.ErrTooLong) {
+			h.log.Warn("Interaction data too large", zap.Int("userID", userID), zap.Int64("limit", limits.MaxBodyBytes))
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Interaction data too large"})
+			return
+		}
+		h.log.Error("Failed to parse interaction chunks", zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
 		return
 	}
+	for _, p := range payloads {
+		if n := metrics.EventCount(&p.InteractionData); n > limits.MaxChunkEvents {
+			h.log.Warn("Interaction chunk has too many events",
+				zap.Int("userID", userID),
+				zap.String("sessionID", p.SessionID),
+				zap.Int("seq", p.Sequence),
+				zap.Int("events", n))
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Interaction chunk has too many events"})
+			return
+		}
+	}
 
-	calculatedMetrics := metrics.CalculateInteractionMetrics(&interactionData)
+	// Keys are reduced to categories before anything is stored or logged. The
+	// client already does this, but older clients may still send raw keys.
+	if config.Conf.Interaction.KeystrokeCapture != config.KeystrokeRaw {
+		for i := range payloads {
+			metrics.RedactKeys(payloads[i].KeyboardEvents)
+		}
+	}
 
-	// **THE FIX IS HERE: Use the new function to get the most recent assessment.**
-	state, err := repository.GetMostRecentAssessmentState(uint(userID))
-	if err != nil {
-		h.log.Error("Failed to get assessment state", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get assessment state"})
+	states := make(map[uint]*models.AssessmentState)
+	chunks := make(map[uint][]models.InteractionChunk)
+	var quarantined []models.QuarantinedInteractionChunk
+	acked := make([]gin.H, 0, len(payloads))
+
+	for _, p := range payloads {
+		quality := metrics.SanitizeInteractionData(&p.InteractionData, limits)
+		if quality.DroppedEvents > 0 {
+			h.log.Warn("Dropped implausible interaction events",
+				zap.Int("userID", userID),
+				zap.Uint("assessmentID", p.AssessmentID),
+				zap.String("sessionID", p.SessionID),
+				zap.Int("seq", p.Sequence),
+				zap.Int("dropped", quality.DroppedEvents),
+				zap.Int("clockJumps", quality.ClockJumps))
+		}
+
+		data, err := json.Marshal(p.InteractionData)
+		if err != nil {
+			h.log.Error("Failed to encode interaction chunk", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data"})
+			return
+		}
+		acked = append(acked, gin.H{"sessionId": p.SessionID, "seq": p.Sequence})
+
+		state, ok := states[p.AssessmentID]
+		if !ok {
+			state, err = repository.GetUserAssessmentState(c, p.AssessmentID, uint(userID))
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				h.log.Error("Failed to get assessment state", zap.Error(err), zap.Uint("assessmentID", p.AssessmentID))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get assessment state"})
+				return
+			}
+			states[p.AssessmentID] = state // nil when the assessment is not the user's
+		}
+
+		if reason := h.attributionError(state, p); reason != "" {
+			h.log.Warn("Quarantining interaction chunk",
+				zap.String("reason", reason),
+				zap.Int("userID", userID),
+				zap.Uint("assessmentID", p.AssessmentID),
+				zap.String("sessionID", p.SessionID),
+				zap.Int("seq", p.Sequence))
+			quarantined = append(quarantined, models.QuarantinedInteractionChunk{
+				UserID:       userID,
+				AssessmentID: p.AssessmentID,
+				SessionID:    p.SessionID,
+				Sequence:     p.Sequence,
+				QuestionID:   p.QuestionID,
+				Reason:       reason,
+				Data:         data,
+			})
+			continue
+		}
+
+		chunks[p.AssessmentID] = append(chunks[p.AssessmentID], models.InteractionChunk{
+			AssessmentID:  p.AssessmentID,
+			SessionID:     p.SessionID,
+			Sequence:      p.Sequence,
+			SessionStart:  p.SessionStart,
+			QuestionID:    p.QuestionID,
+			DroppedEvents: quality.DroppedEvents,
+			ClockJumps:    quality.ClockJumps,
+			Data:          data,
+		})
+	}
+
+	if err := repository.QuarantineInteractionChunks(c, quarantined); err != nil {
+		h.log.Error("Failed to quarantine interaction chunks", zap.Error(err), zap.Int("userID", userID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save interaction data"})
 		return
 	}
 
-	for _, metric := range calculatedMetrics.GlobalMetrics {
-		metric.AssessmentID = uint(state.ID)
-		if err := repository.SaveMetric(metric); err != nil {
-			h.log.Error("Failed to save global metric", zap.Error(err))
+	for assessmentID, assessmentChunks := range chunks {
+		stored, err := repository.SaveInteractionChunks(c, assessmentChunks)
+		if err != nil {
+			h.log.Error("Failed to save interaction chunks", zap.Error(err), zap.Uint("assessmentID", assessmentID))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save interaction data"})
+			return
+		}
+
+		// Chunks sent while the page unloads can arrive after the final question was
+		// answered, so a completed assessment has its metrics recomputed.
+		if stored > 0 && states[assessmentID].IsComplete {
+			if err := services.FinalizeInteractionMetrics(c, h.Assessment, assessmentID); err != nil {
+				h.log.Error("Failed to finalize interaction metrics", zap.Error(err), zap.Uint("assessmentID", assessmentID))
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"acked": acked})
+}
+
+// attributionError explains why a chunk cannot be saved against the assessment it
+// names, or returns an empty string if it can. state is nil when the assessment
+// does not exist or belongs to another user.
+func (h *MetricsHandler) attributionError(state *models.AssessmentState, p models.InteractionChunkPayload) string {
+	if state == nil {
+		return "assessment not found for user"
+	}
+
+	inOrder := make(map[string]bool, len(state.QuestionOrder))
+	for _, idx := range state.QuestionOrder {
+		if int(idx) < len(h.Assessment.Questions) {
+			inOrder[h.Assessment.Questions[idx].ID] = true
 		}
 	}
+	// Events recorded outside any question carry no question ID and count as global.
+	known := func(questionID string) bool { return questionID == "" || inOrder[questionID] }
 
-	for _, metric := range calculatedMetrics.QuestionMetrics {
-		metric.AssessmentID = uint(state.ID)
-		if err := repository.SaveMetric(metric); err != nil {
-			h.log.Error("Failed to save question metric", zap.Error(err))
+	if !known(p.QuestionID) {
+		return fmt.Sprintf("question %q is not part of the assessment", p.QuestionID)
+	}
+	for _, m := range p.MouseMovements {
+		if !known(m.QuestionID) {
+			return fmt.Sprintf("question %q is not part of the assessment", m.QuestionID)
+		}
+	}
+	for _, i := range p.MouseInteractions {
+		if !known(i.QuestionID) {
+			return fmt.Sprintf("question %q is not part of the assessment", i.QuestionID)
+		}
+	}
+	for _, k := range p.KeyboardEvents {
+		if !known(k.QuestionID) {
+			return fmt.Sprintf("question %q is not part of the assessment", k.QuestionID)
 		}
 	}
+	for _, g := range p.TouchGestures {
+		if !known(g.QuestionID) {
+			return fmt.Sprintf("question %q is not part of the assessment", g.QuestionID)
+		}
+	}
+	for _, e := range p.PageEvents {
+		if !known(e.QuestionID) {
+			return fmt.Sprintf("question %q is not part of the assessment", e.QuestionID)
+		}
+	}
+	return ""
+}
+
+// parseInteractionChunks reads the NDJSON request body. Blank lines are skipped.
+// No line may be longer than maxLine bytes.
+func parseInteractionChunks(c *gin.Context, maxLine int64) ([]models.InteractionChunkPayload, error) {
+	var payloads []models.InteractionChunkPayload
 
-	c.Status(http.StatusOK)
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), int(maxLine))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var p models.InteractionChunkPayload
+		if err := json.Unmarshal(line, &p); err != nil {
+			return nil, err
+		}
+		if p.SessionID == "" || len(p.SessionID) > 64 || p.Sequence < 0 {
+			return nil, errors.New("interaction chunk has an invalid session ID or sequence number")
+		}
+		payloads = append(payloads, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return payloads, nil
 }
@@ -2,12 +2,24 @@
 package handlers
 
 import (
+	"crapp-go/internal/cognitive"
+	"crapp-go/internal/config"
 	"crapp-go/internal/models"
+	"crapp-go/internal/norms"
 	"crapp-go/internal/repository"
+	"crapp-go/internal/services"
+	"crapp-go/internal/stats"
 	"crapp-go/views"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/a-h/templ"
 	"github.com/gin-contrib/sessions"
@@ -16,6 +28,7 @@ import (
 
 	"github.com/go-echarts/go-echarts/v2/charts"
 	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/go-echarts/go-echarts/v2/types"
 )
 
 type ResultsHandler struct {
@@ -38,16 +51,33 @@ func (h *ResultsHandler) ShowResults(c *gin.Context) {
 	assessment := h.Assessment
 	primaryTaskID := c.Query("symptom") // Renamed for clarity in the template, but it's the task/question ID
 	metricKey := c.Query("metric")
+	showFlagged := c.Query("flagged") == "true"
+	algorithmVersion, _ := strconv.Atoi(c.Query("version")) // 0 (latest recomputation) when absent
+	scale := c.DefaultQuery("scale", scaleRaw)
+	window, _ := strconv.Atoi(c.Query("window"))
+	trendMethod, window := services.TrendSettings(c.Query("trend"), window)
+	maxLag := config.Conf.Results.MaxLagDays
+	lag, _ := strconv.Atoi(c.Query("lag"))
+	lag = max(-maxLag, min(lag, maxLag))
+
+	// Every chart is limited to the same local date range, and the timelines are
+	// aggregated per day, week or month when asked.
+	timeZone, _ := userLocation(c)
+	seriesRange, err := parseSeriesRange(c, timeZone)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
 
 	// Group questions by their function for the dropdown
 	questionGroups := make(map[string][]models.Question)
 	for _, q := range assessment.Questions {
 		var groupKey string
-		switch q.Type {
+		switch {
 		// Scales should use the radio type, otherwise use a drop down
-		case "radio":
+		case q.Type == "radio":
 			groupKey = "symptom"
-		case "cpt", "tmt", "dst":
+		case cognitive.IsCognitive(q.Type):
 			groupKey = q.Type
 		default:
 			groupKey = q.MetricsType
@@ -87,62 +117,219 @@ func (h *ResultsHandler) ShowResults(c *gin.Context) {
 		metricLabel = availableMetrics[0].Label
 	}
 
-	var correlationSymptomID string
+	selectedKey := repository.SeriesKey{QuestionID: primaryTaskID, MetricKey: metricKey}
+
+	// Any two series can be correlated. The x axis defaults to the charted metric
+	// and the y axis to the first symptom score.
 	var correlationData []repository.CorrelationDataPoint
-	showCorrelationChart := false
+	seriesOptions := correlationSeries(assessment.Questions)
+	showCorrelationChart := len(seriesOptions) >= 2
+	var correlationX, correlationY models.MetricOption
+	if showCorrelationChart {
+		correlationX = pickSeries(seriesOptions, c.Query("corr_x"), selectedKey.String(), "")
+		defaultY := ""
+		if symptoms := questionGroups["symptom"]; len(symptoms) > 0 {
+			defaultY = repository.SeriesKey{QuestionID: symptoms[0].ID, MetricKey: symptoms[0].ID}.String()
+		}
+		correlationY = pickSeries(seriesOptions, c.Query("corr_y"), defaultY, correlationX.Value)
+	}
 
-	// Correlation is only shown if the selected item is a task, not a symptom report.
-	if selectedQuestion.Type == "radio" {
-		if len(questionGroups["symptom"]) > 0 {
-			showCorrelationChart = true
-			correlationSymptomID = questionGroups["symptom"][0].ID // Correlate against the first symptom
+	// Several series can be overlaid on one time axis. The selection is kept in the
+	// URL so the view can be bookmarked.
+	var overlaySelection []models.MetricOption
+	for _, value := range c.QueryArray("overlay") {
+		if i := slices.IndexFunc(seriesOptions, func(o models.MetricOption) bool { return o.Value == value }); i >= 0 {
+			overlaySelection = append(overlaySelection, seriesOptions[i])
+		}
+	}
+	overlayMode := c.DefaultQuery("overlay_scale", overlayAxes)
+	if overlayMode != overlayNormalized {
+		overlayMode = overlayAxes
+	}
+	var overlayOptionsJSON []byte
+	if len(overlaySelection) > 0 {
+		keys := make([]repository.SeriesKey, 0, len(overlaySelection))
+		for _, option := range overlaySelection {
+			key, _ := repository.ParseSeriesKey(option.Value)
+			keys = append(keys, key)
+		}
+		overlayFilter := seriesRange
+		overlayFilter.ValidityStatus = models.ValidityValid
+		overlayData, err := repository.GetTimelineData(c, userID, keys, overlayFilter)
+		if err != nil {
+			h.log.Error("Failed to get overlay data", zap.Error(err), zap.Strings("series", c.QueryArray("overlay")))
+			c.String(http.StatusInternalServerError, "Failed to load timeline data")
+			return
 		}
+		overlayOptionsJSON, _ = json.Marshal(generateOverlayChart(overlaySelection, repository.SplitSeries(overlayData), overlayMode).JSON())
+	}
+
+	// Values computed by older scoring algorithms can be charted on their own; an
+	// unknown version falls back to the latest recomputation of each run.
+	algorithmVersions, err := repository.GetAlgorithmVersions(c, userID, primaryTaskID, metricKey)
+	if err != nil {
+		h.log.Error("Failed to get algorithm versions", zap.Error(err), zap.String("taskID", primaryTaskID), zap.String("metricKey", metricKey))
+		c.String(http.StatusInternalServerError, "Failed to load timeline data")
+		return
+	}
+	if !slices.Contains(algorithmVersions, algorithmVersion) {
+		algorithmVersion = 0
 	}
 
 	// Fetch data for the timeline chart.
-	timelineData, err := repository.GetTimelineData(c, userID, primaryTaskID, metricKey)
+	timelineFilter := seriesRange
+	timelineFilter.ValidityStatus = models.ValidityValid
+	timelineFilter.AlgorithmVersion = algorithmVersion
+	timelineData, err := repository.GetTimelineData(c, userID, []repository.SeriesKey{selectedKey}, timelineFilter)
 	if err != nil {
 		h.log.Error("Failed to get timeline data", zap.Error(err), zap.String("taskID", primaryTaskID), zap.String("metricKey", metricKey))
 		c.String(http.StatusInternalServerError, "Failed to load timeline data")
 		return
 	}
 
+	// Showing the results never changes the stored baseline; it is only changed
+	// through SaveBaseline.
+	baseline, err := services.ViewBaseline(c, userID, primaryTaskID, metricKey, algorithmVersion)
+	if err != nil {
+		h.log.Error("Failed to resolve baseline", zap.Error(err), zap.String("taskID", primaryTaskID), zap.String("metricKey", metricKey))
+		c.String(http.StatusInternalServerError, "Failed to load baseline")
+		return
+	}
+	if !canScale(baseline, scale) {
+		scale = scaleRaw
+	}
+
+	// Reliable changes are detected on the latest recomputation of every run, so
+	// they are only marked on that timeline.
+	var changeEvents []models.ChangeEvent
+	if algorithmVersion == 0 {
+		changeEvents, err = repository.GetChangeEvents(c, userID, primaryTaskID, metricKey)
+		if err != nil {
+			h.log.Error("Failed to get change events", zap.Error(err), zap.String("taskID", primaryTaskID), zap.String("metricKey", metricKey))
+			c.String(http.StatusInternalServerError, "Failed to load change events")
+			return
+		}
+	}
+
+	// Runs that failed validity rules are only shown on request, as a separate series and list.
+	var flaggedData []repository.TimelineDataPoint
+	var flaggedRuns []repository.FlaggedRun
+	if test, ok := cognitive.Get(selectedQuestion.Type); ok && showFlagged {
+		// Flagged runs are always shown one by one, within the date range.
+		flaggedData, err = repository.GetTimelineData(c, userID, []repository.SeriesKey{selectedKey}, repository.SeriesFilter{
+			ValidityStatus:   models.ValidityInvalid,
+			AlgorithmVersion: algorithmVersion,
+			From:             seriesRange.From,
+			To:               seriesRange.To,
+			TimeZone:         timeZone,
+		})
+		if err != nil {
+			h.log.Error("Failed to get flagged timeline data", zap.Error(err), zap.String("taskID", primaryTaskID), zap.String("metricKey", metricKey))
+			c.String(http.StatusInternalServerError, "Failed to load timeline data")
+			return
+		}
+		flaggedRuns, err = repository.GetFlaggedRuns(c, userID, test)
+		if err != nil {
+			h.log.Error("Failed to get flagged runs", zap.Error(err), zap.String("taskID", primaryTaskID))
+			c.String(http.StatusInternalServerError, "Failed to load flagged runs")
+			return
+		}
+	}
+
+	// Cognitive test scores are placed in the normative group matching the user's
+	// demographics at the time of each run, when a normative table is loaded.
+	var normedResults []norms.NormedResult
+	if test, ok := cognitive.Get(selectedQuestion.Type); ok {
+		entries, err := repository.GetNormativeEntries(c, test.Type(), metricKey)
+		if err != nil {
+			h.log.Error("Failed to get normative data", zap.Error(err), zap.String("testType", test.Type()), zap.String("metricKey", metricKey))
+			c.String(http.StatusInternalServerError, "Failed to load normative data")
+			return
+		}
+		if len(entries) > 0 {
+			// Each run is scored on its own, on the latest recomputation, whatever
+			// aggregation or algorithm version the chart shows.
+			runs, err := repository.GetTimelineData(c, userID, []repository.SeriesKey{selectedKey}, repository.SeriesFilter{
+				ValidityStatus: models.ValidityValid,
+				From:           seriesRange.From,
+				To:             seriesRange.To,
+				TimeZone:       timeZone,
+			})
+			if err != nil {
+				h.log.Error("Failed to get results for normative scoring", zap.Error(err), zap.String("taskID", primaryTaskID), zap.String("metricKey", metricKey))
+				c.String(http.StatusInternalServerError, "Failed to load normative data")
+				return
+			}
+			var demographics models.Demographics
+			if user, ok := c.Get("user"); ok {
+				demographics = user.(*models.User).Demographics
+			}
+			normedResults = scoreAgainstNorms(runs, entries, demographics)
+		}
+	}
+
 	// Fetch data for the correlation chart if needed.
 	if showCorrelationChart {
-		var err error
-		correlationData, err = repository.GetCorrelationData(c, userID, correlationSymptomID, primaryTaskID, metricKey)
+		x, _ := repository.ParseSeriesKey(correlationX.Value)
+		y, _ := repository.ParseSeriesKey(correlationY.Value)
+		correlationData, err = repository.GetCorrelationData(c, userID, x, y, lag, seriesRange)
 		if err != nil {
-			h.log.Error("Failed to get correlation data", zap.Error(err), zap.String("symptomID", correlationSymptomID), zap.String("taskID", primaryTaskID), zap.String("metricKey", metricKey))
+			h.log.Error("Failed to get correlation data", zap.String("x", correlationX.Value), zap.String("y", correlationY.Value), zap.Error(err))
 			c.String(http.StatusInternalServerError, "Failed to load correlation data")
 			return
 		}
 	}
 
-	timelineChart := generateTimelineChart(timelineData, metricLabel)
-	correlationChart := generateCorrelationChart(correlationData, metricLabel, correlationSymptomID)
+	trend := services.TimelineTrend(timelineData, selectedQuestion, metricKey, trendMethod, window)
+
+	timelineChart := generateTimelineChart(timelineData, flaggedData, changeEvents, trend, metricLabel, baseline, scale)
+	correlationChart := generateCorrelationChart(correlationData, correlationX.Label, correlationY.Label, lag)
 
 	timelineOptionsJSON, _ := json.Marshal(timelineChart.JSON())
+	// Points of the charted metric open their assessment when clicked.
+	pointAssessments := make([]uint, len(timelineData))
+	for i, point := range timelineData {
+		pointAssessments[i] = point.AssessmentID
+	}
+	timelinePointsJSON, _ := json.Marshal(pointAssessments)
 	correlationOptionsJSON, _ := json.Marshal(correlationChart.JSON())
 
 	csrfToken, _ := c.Get("csrf_token")
 	cspNonce, _ := c.Get("csp_nonce")
 
 	metricsTypeForExplanation := selectedQuestion.Type
-	if metricsTypeForExplanation != "cpt" && metricsTypeForExplanation != "tmt" && metricsTypeForExplanation != "dst" {
+	if !cognitive.IsCognitive(metricsTypeForExplanation) {
 		metricsTypeForExplanation = selectedQuestion.MetricsType
 	}
 
-	component := views.ResultsCharts(
-		questionGroups,
-		availableMetrics,
-		primaryTaskID,
-		metricKey,
-		string(timelineOptionsJSON),
-		string(correlationOptionsJSON),
-		cspNonce.(string),
-		metricsTypeForExplanation,
-		showCorrelationChart,
-	)
+	component := views.ResultsCharts(views.ResultsView{
+		QuestionGroups:     questionGroups,
+		Metrics:            availableMetrics,
+		SelectedQuestion:   primaryTaskID,
+		SelectedMetric:     metricKey,
+		MetricsType:        metricsTypeForExplanation,
+		IsCognitiveTest:    cognitive.IsCognitive(selectedQuestion.Type),
+		AlgorithmVersions:  algorithmVersions,
+		SelectedVersion:    algorithmVersion,
+		Range:              seriesRange,
+		Baseline:           baseline,
+		Scale:              scale,
+		Trend:              trend,
+		TimelineOptions:    string(timelineOptionsJSON),
+		TimelinePoints:     string(timelinePointsJSON),
+		SeriesOptions:      seriesOptions,
+		Overlay:            overlaySelection,
+		OverlayMode:        overlayMode,
+		OverlayOptions:     string(overlayOptionsJSON),
+		ShowCorrelation:    showCorrelationChart,
+		CorrelationX:       correlationX.Value,
+		CorrelationY:       correlationY.Value,
+		Lag:                lag,
+		CorrelationOptions: string(correlationOptionsJSON),
+		NormedResults:      normedResults,
+		ShowFlagged:        showFlagged,
+		FlaggedRuns:        flaggedRuns,
+	})
 
 	if c.GetHeader("HX-Request") == "true" {
 		component.Render(c.Request.Context(), c.Writer)
@@ -154,61 +341,175 @@ func (h *ResultsHandler) ShowResults(c *gin.Context) {
 	}
 }
 
+// SaveBaseline stores the baseline window the user chose for the charted metric,
+// recomputing the baseline and its change events, then shows the results again
+// with every other option the user had chosen.
+func (h *ResultsHandler) SaveBaseline(c *gin.Context) {
+	session := sessions.Default(c)
+	userID, ok := session.Get("userID").(int)
+	if !ok {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	questionIndex := slices.IndexFunc(h.Assessment.Questions, func(q models.Question) bool { return q.ID == c.PostForm("symptom") })
+	if questionIndex < 0 {
+		c.String(http.StatusBadRequest, "Unknown question")
+		return
+	}
+	question := h.Assessment.Questions[questionIndex]
+	metricKey := c.PostForm("metric")
+	if !slices.ContainsFunc(getAvailableMetrics(question), func(m models.MetricOption) bool { return m.Value == metricKey }) {
+		c.String(http.StatusBadRequest, "Unknown metric")
+		return
+	}
+	window, err := parseBaselineWindow(c)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := services.ResolveBaseline(c, userID, question, metricKey, window); err != nil {
+		h.log.Error("Failed to save baseline", zap.Error(err), zap.String("taskID", question.ID), zap.String("metricKey", metricKey))
+		c.String(http.StatusInternalServerError, "Failed to save baseline")
+		return
+	}
+
+	query := url.Values{}
+	for key, values := range c.Request.PostForm {
+		if key != "_csrf" && !strings.HasPrefix(key, "baseline") {
+			query[key] = values
+		}
+	}
+	location, _ := json.Marshal(map[string]string{"path": "/assessment/results?" + query.Encode(), "target": "main#content"})
+	c.Header("HX-Location", string(location))
+	c.Status(http.StatusOK)
+}
+
+// ShowCorrelationMatrix renders a heatmap of the correlations between every pair
+// of symptom scores and task metrics. Clicking a cell opens its scatter plot.
+func (h *ResultsHandler) ShowCorrelationMatrix(c *gin.Context) {
+	session := sessions.Default(c)
+	userID, ok := session.Get("userID").(int)
+	if !ok {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	seriesOptions := correlationSeries(h.Assessment.Questions)
+	keys := make([]repository.SeriesKey, 0, len(seriesOptions))
+	values := make([]string, 0, len(seriesOptions))
+	for _, option := range seriesOptions {
+		key, _ := repository.ParseSeriesKey(option.Value)
+		keys = append(keys, key)
+		values = append(values, option.Value)
+	}
+
+	matrix, err := services.CorrelationMatrix(c, userID, keys)
+	if err != nil {
+		h.log.Error("Failed to compute correlation matrix", zap.Error(err), zap.Int("userID", userID))
+		c.String(http.StatusInternalServerError, "Failed to load correlation matrix")
+		return
+	}
+
+	matrixOptionsJSON, _ := json.Marshal(generateCorrelationMatrixChart(seriesOptions, matrix).JSON())
+	seriesJSON, _ := json.Marshal(values)
+	views.CorrelationMatrix(string(matrixOptionsJSON), string(seriesJSON), len(seriesOptions)).Render(c.Request.Context(), c.Writer)
+}
+
+// pointerMetrics are the mouse, touch and interruption metrics recorded for
+// every question answered with a pointer.
+var pointerMetrics = []models.MetricOption{
+	{Value: "click_precision", Label: "Click Precision"},
+	{Value: "path_efficiency", Label: "Path Efficiency"},
+	{Value: "overshoot_rate", Label: "Overshoot Rate"},
+	{Value: "average_velocity", Label: "Average Velocity"},
+	{Value: "velocity_variability", Label: "Velocity Variability"},
+	{Value: "peak_velocity_time", Label: "Peak Velocity Timing"},
+	{Value: "normalized_jerk", Label: "Movement Jerk"},
+	{Value: "curvature_index", Label: "Curvature Index"},
+	{Value: "submovement_count", Label: "Submovements"},
+	{Value: "fitts_throughput", Label: "Fitts' Throughput"},
+	{Value: "tap_accuracy", Label: "Tap Accuracy"},
+	{Value: "tap_duration", Label: "Tap Duration"},
+	{Value: "swipe_velocity", Label: "Swipe Velocity"},
+	{Value: "multi_touch_error_rate", Label: "Multi-Touch Error Rate"},
+	{Value: "interruption_count", Label: "Interruptions"},
+	{Value: "time_away", Label: "Time Away"},
+}
+
+// decisionMetrics are only recorded for choice questions.
+var decisionMetrics = []models.MetricOption{
+	{Value: "time_to_first_movement", Label: "Time to First Movement"},
+	{Value: "time_to_first_click", Label: "Time to First Click"},
+	{Value: "option_switches", Label: "Option Switches"},
+	{Value: "option_hover_time", Label: "Hover Time per Option"},
+	{Value: "idle_period_count", Label: "Idle Periods"},
+	{Value: "idle_time", Label: "Idle Time"},
+}
+
 // getAvailableMetrics now correctly combines metrics from the Question's TYPE and its Metrics TYPE.
 func getAvailableMetrics(question models.Question) []models.MetricOption {
-	// For cognitive tests, use the test type
-	switch question.Type {
-	case "cpt":
+	// For cognitive tests, use the test's own metric catalog
+	if test, ok := cognitive.Get(question.Type); ok {
+		return test.Metrics()
+	}
+
+	// For regular questions, use the metrics_type
+	if question.MetricsType == "keyboard" {
 		return []models.MetricOption{
-			{Value: "reaction_time", Label: "Reaction Time (ms)"},
-			{Value: "detection_rate", Label: "Detection Rate (%)"},
-			{Value: "omission_error_rate", Label: "Omission Error Rate (%)"},
-			{Value: "commission_error_rate", Label: "Commission Error Rate (%)"},
+			{Value: "typing_speed", Label: "Typing Speed"},
+			{Value: "average_inter_key_interval", Label: "Inter-Key Interval"},
+			{Value: "typing_rhythm_variability", Label: "Typing Rhythm Variability"},
+			{Value: "correction_rate", Label: "Correction Rate"},
+			{Value: "keyboard_fluency", Label: "Keyboard Fluency Score"},
 		}
-	case "tmt":
-		return []models.MetricOption{
-			{Value: "part_a_time", Label: "Part A Time (ms)"},
-			{Value: "part_b_time", Label: "Part B Time (ms)"},
-			{Value: "b_a_ratio", Label: "B/A Ratio"},
-			{Value: "part_a_errors", Label: "Part A Errors"},
-			{Value: "part_b_errors", Label: "Part B Errors"},
+	}
+
+	// Mouse metrics, also the fallback for any other metrics_type
+	options := slices.Clone(pointerMetrics)
+	if question.IsChoice() {
+		options = append(options, decisionMetrics...)
+	}
+	return options
+}
+
+// correlationSeries lists the series that can be placed on a correlation axis:
+// every symptom score, then every metric of the other questions and the
+// cognitive tests. Each option's value is its encoded repository.SeriesKey.
+func correlationSeries(questions []models.Question) []models.MetricOption {
+	var symptoms, tasks []models.MetricOption
+	for _, q := range questions {
+		if q.Type == "radio" {
+			key := repository.SeriesKey{QuestionID: q.ID, MetricKey: q.ID}
+			symptoms = append(symptoms, models.MetricOption{Value: key.String(), Label: q.Title})
+			continue
 		}
-	case "dst":
-		return []models.MetricOption{
-			{Value: "highest_span", Label: "Highest Span Achieved"},
-			{Value: "correct_trials", Label: "Correct Trials"},
-			{Value: "total_trials", Label: "Total Trials"},
+		for _, metric := range getAvailableMetrics(q) {
+			key := repository.SeriesKey{QuestionID: q.ID, MetricKey: metric.Value}
+			tasks = append(tasks, models.MetricOption{Value: key.String(), Label: q.Title + ": " + metric.Label})
 		}
-	default:
-		// For regular questions, use the metrics_type
-		switch question.MetricsType {
-		case "keyboard":
-			return []models.MetricOption{
-				{Value: "typing_speed", Label: "Typing Speed"},
-				{Value: "average_inter_key_interval", Label: "Inter-Key Interval"},
-				{Value: "typing_rhythm_variability", Label: "Typing Rhythm Variability"},
-				{Value: "correction_rate", Label: "Correction Rate"},
-				{Value: "keyboard_fluency", Label: "Keyboard Fluency Score"},
-			}
-		case "mouse":
-			return []models.MetricOption{
-				{Value: "click_precision", Label: "Click Precision"},
-				{Value: "path_efficiency", Label: "Path Efficiency"},
-				{Value: "overshoot_rate", Label: "Overshoot Rate"},
-				{Value: "average_velocity", Label: "Average Velocity"},
-				{Value: "velocity_variability", Label: "Velocity Variability"},
-			}
-		default:
-			// Fallback to mouse metrics
-			return []models.MetricOption{
-				{Value: "click_precision", Label: "Click Precision"},
-				{Value: "path_efficiency", Label: "Path Efficiency"},
-				{Value: "overshoot_rate", Label: "Overshoot Rate"},
-				{Value: "average_velocity", Label: "Average Velocity"},
-				{Value: "velocity_variability", Label: "Velocity Variability"},
+	}
+	return append(symptoms, tasks...)
+}
+
+// pickSeries returns the requested series option, falling back to the default and
+// then to the first option. The series in exclude is skipped unless it is asked
+// for explicitly, so the two axes default to different series.
+func pickSeries(options []models.MetricOption, requested, fallback, exclude string) models.MetricOption {
+	for _, value := range []string{requested, fallback} {
+		for _, option := range options {
+			if option.Value == value && (value == requested || value != exclude) {
+				return option
 			}
 		}
 	}
+	for _, option := range options {
+		if option.Value != exclude {
+			return option
+		}
+	}
+	return options[0]
 }
 
 func getQuestionByID(id string, questions []models.Question) (models.Question, bool) {
@@ -220,12 +521,135 @@ func getQuestionByID(id string, questions []models.Question) (models.Question, b
 	return models.Question{}, false
 }
 
-func generateTimelineChart(data []repository.TimelineDataPoint, metricLabel string) *charts.Line {
+// scoreAgainstNorms places each result in its normative group, newest first.
+func scoreAgainstNorms(data []repository.TimelineDataPoint, entries []models.NormativeEntry, demographics models.Demographics) []norms.NormedResult {
+	results := make([]norms.NormedResult, 0, len(data))
+	for i := len(data) - 1; i >= 0; i-- {
+		result := norms.NormedResult{Date: data[i].Date, Value: data[i].Value}
+		if group := norms.Match(entries, demographics, data[i].Date); group != nil {
+			score := norms.Compute(group, data[i].Value)
+			result.Group, result.Score = group, &score
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Timeline scales: raw values, or change from the user's baseline.
+const (
+	scaleRaw     = "raw"
+	scaleZScore  = "z"
+	scalePercent = "percent"
+)
+
+// Ways to fit several series with different units on the overlay chart.
+const (
+	overlayAxes       = "axes"       // Each series on its own y-axis
+	overlayNormalized = "normalized" // Each series as z-scores of its own values
+)
+
+// parseSeriesRange reads the local date range and the aggregation of the results
+// charts. Missing values leave the range open and keep one point per assessment.
+func parseSeriesRange(c *gin.Context, timeZone string) (repository.SeriesFilter, error) {
+	filter := repository.SeriesFilter{
+		TimeZone:    timeZone,
+		Aggregation: c.DefaultQuery("aggregate", repository.AggregateAssessment),
+		Statistic:   c.DefaultQuery("stat", repository.StatMean),
+	}
+	for name, date := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(name); value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s date %q", name, value)
+			}
+			*date = parsed
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return filter, fmt.Errorf("the date range ends before it starts")
+	}
+	if !slices.Contains([]string{repository.AggregateAssessment, repository.AggregateDay, repository.AggregateWeek, repository.AggregateMonth}, filter.Aggregation) {
+		return filter, fmt.Errorf("unknown aggregation %q", filter.Aggregation)
+	}
+	if !slices.Contains([]string{repository.StatMean, repository.StatMedian, repository.StatMin, repository.StatMax}, filter.Statistic) {
+		return filter, fmt.Errorf("unknown statistic %q", filter.Statistic)
+	}
+	return filter, nil
+}
+
+// parseBaselineWindow reads the baseline window from the posted form. A date
+// range without dates covers the last 30 days, so switching methods stores a
+// window the user can then adjust.
+func parseBaselineWindow(c *gin.Context) (*models.Baseline, error) {
+	switch c.PostForm("baseline") {
+	case models.BaselineFirstN:
+		n, err := strconv.Atoi(c.DefaultPostForm("baseline_n", strconv.Itoa(config.Conf.Results.BaselineAssessments)))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid number of baseline assessments")
+		}
+		return &models.Baseline{Method: models.BaselineFirstN, FirstN: n}, nil
+	case models.BaselineDateRange:
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		start, end := today.AddDate(0, 0, -30), today
+		var err error
+		if v := c.PostForm("baseline_start"); v != "" {
+			if start, err = time.Parse("2006-01-02", v); err != nil {
+				return nil, fmt.Errorf("invalid baseline start date")
+			}
+		}
+		if v := c.PostForm("baseline_end"); v != "" {
+			if end, err = time.Parse("2006-01-02", v); err != nil {
+				return nil, fmt.Errorf("invalid baseline end date")
+			}
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("baseline end date is before its start date")
+		}
+		return &models.Baseline{Method: models.BaselineDateRange, StartDate: &start, EndDate: &end}, nil
+	default:
+		return nil, fmt.Errorf("invalid baseline method")
+	}
+}
+
+// canScale reports whether values can be shown on a scale relative to baseline.
+// z-scores need spread in the baseline, and percent change a non-zero mean.
+func canScale(baseline *models.Baseline, scale string) bool {
+	switch scale {
+	case scaleRaw:
+		return true
+	case scaleZScore:
+		return baseline.HasSpread()
+	case scalePercent:
+		return baseline.N > 0 && baseline.Mean != 0
+	}
+	return false
+}
+
+// scaleValue converts a raw value to the chosen scale.
+func scaleValue(baseline *models.Baseline, scale string, value float64) float64 {
+	switch scale {
+	case scaleZScore:
+		return baseline.ZScore(value)
+	case scalePercent:
+		return baseline.PercentChange(value)
+	}
+	return value
+}
+
+func generateTimelineChart(data, flagged []repository.TimelineDataPoint, changes []models.ChangeEvent, trend *services.Trend, metricLabel string, baseline *models.Baseline, scale string) *charts.Line {
+	subtitle := metricLabel
+	switch scale {
+	case scaleZScore:
+		subtitle += " (z-score vs. baseline)"
+	case scalePercent:
+		subtitle += " (% change from baseline)"
+	}
+
 	line := charts.NewLine()
 	line.SetGlobalOptions(
 		charts.WithTitleOpts(opts.Title{
 			Title:    "Metric Over Time",
-			Subtitle: metricLabel,
+			Subtitle: subtitle,
 		}),
 		charts.WithXAxisOpts(opts.XAxis{
 			Type: "time", // Change type from "category" to "time"
@@ -242,35 +666,300 @@ func generateTimelineChart(data []repository.TimelineDataPoint, metricLabel stri
 	// Create data points in the format [date, value]
 	items := make([]opts.LineData, 0)
 	for _, point := range data {
-		items = append(items, opts.LineData{Value: []interface{}{point.Date, point.Value}})
+		items = append(items, opts.LineData{Value: []interface{}{point.Date, scaleValue(baseline, scale, point.Value)}})
 	}
 
 	line.AddSeries(metricLabel, items).SetSeriesOptions(charts.WithLineStyleOpts(opts.LineStyle{Width: 2}))
+
+	// The rolling mean and trend line are drawn without markers so the measured
+	// points stay the only ones that can be hovered.
+	derived := []struct {
+		name   string
+		points []services.TrendPoint
+		color  string
+		dash   string
+	}{
+		{fmt.Sprintf("Rolling mean (%d)", trend.Window), trend.RollingMean, "#6b7280", "dashed"},
+		{"Trend (" + trend.Method + ")", trend.Line, "#ea580c", "solid"},
+	}
+	for _, series := range derived {
+		if len(series.points) == 0 {
+			continue
+		}
+		seriesItems := make([]opts.LineData, 0, len(series.points))
+		for _, point := range series.points {
+			seriesItems = append(seriesItems, opts.LineData{Value: []interface{}{point.Date, scaleValue(baseline, scale, point.Value)}})
+		}
+		line.AddSeries(series.name, seriesItems,
+			charts.WithLineStyleOpts(opts.LineStyle{Width: 2, Color: series.color, Type: series.dash}),
+			charts.WithItemStyleOpts(opts.ItemStyle{Color: series.color}),
+			charts.WithLineChartOpts(opts.LineChart{ShowSymbol: opts.Bool(false)}),
+		)
+	}
+
+	// The baseline band spans one standard deviation either side of the mean.
+	if baseline.N > 0 {
+		mean := scaleValue(baseline, scale, baseline.Mean)
+		low := scaleValue(baseline, scale, baseline.Mean-baseline.SD)
+		high := scaleValue(baseline, scale, baseline.Mean+baseline.SD)
+		series := &line.MultiSeries[0]
+		series.MarkLines = &opts.MarkLines{
+			Data: []interface{}{opts.MarkLineNameYAxisItem{Name: "Baseline", YAxis: mean}},
+			MarkLineStyle: opts.MarkLineStyle{
+				Symbol:    []string{"none", "none"},
+				LineStyle: &opts.LineStyle{Color: "#16a34a", Type: "dashed"},
+			},
+		}
+		if baseline.SD > 0 {
+			series.MarkAreas = &opts.MarkAreas{
+				Data: []interface{}{[]opts.MarkAreaNameYAxisItem{{Name: "Baseline ± 1 SD", YAxis: low}, {YAxis: high}}},
+				MarkAreaStyle: opts.MarkAreaStyle{
+					ItemStyle: &opts.ItemStyle{Color: "#16a34a", Opacity: opts.Float(0.12)},
+				},
+			}
+		}
+	}
+
+	// Flagged runs are drawn as unconnected points so they don't distort the trend line.
+	if len(flagged) > 0 {
+		flaggedItems := make([]opts.ScatterData, 0, len(flagged))
+		for _, point := range flagged {
+			flaggedItems = append(flaggedItems, opts.ScatterData{Value: []interface{}{point.Date, scaleValue(baseline, scale, point.Value)}})
+		}
+		line.MultiSeries = append(line.MultiSeries, charts.SingleSeries{
+			Name:      "Flagged runs",
+			Type:      types.ChartScatter,
+			Data:      flaggedItems,
+			ItemStyle: &opts.ItemStyle{Color: "#dc2626"},
+		})
+	}
+
+	// Reliable changes are marked over the points they were detected at.
+	for _, direction := range []struct {
+		value, name, color, symbol string
+	}{
+		{models.ChangeImprovement, "Reliable improvement", "#16a34a", "triangle"},
+		{models.ChangeDecline, "Reliable decline", "#b45309", "diamond"},
+	} {
+		markers := make([]opts.ScatterData, 0)
+		for _, change := range changes {
+			if change.Direction == direction.value {
+				markers = append(markers, opts.ScatterData{Value: []interface{}{change.Date, scaleValue(baseline, scale, change.Value)}})
+			}
+		}
+		if len(markers) == 0 {
+			continue
+		}
+		line.MultiSeries = append(line.MultiSeries, charts.SingleSeries{
+			Name:       direction.name,
+			Type:       types.ChartScatter,
+			Data:       markers,
+			Symbol:     direction.symbol,
+			SymbolSize: 14,
+			ItemStyle:  &opts.ItemStyle{Color: direction.color},
+		})
+	}
 	return line
 }
 
-func generateCorrelationChart(data []repository.CorrelationDataPoint, metricKey, symptomKey string) *charts.Scatter {
+func generateCorrelationChart(data []repository.CorrelationDataPoint, xLabel, yLabel string, lag int) *charts.Scatter {
+	x := make([]float64, len(data))
+	y := make([]float64, len(data))
+	for i, point := range data {
+		x[i], y[i] = point.X, point.Y
+	}
+
+	title := "Correlation"
+	switch {
+	case lag > 0:
+		title += fmt.Sprintf(" (y %s later)", pluralDays(lag))
+	case lag < 0:
+		title += fmt.Sprintf(" (y %s earlier)", pluralDays(-lag))
+	}
+
 	scatter := charts.NewScatter()
 	scatter.SetGlobalOptions(
 		charts.WithTitleOpts(opts.Title{
-			Title: "Metric vs. Symptom Correlation",
+			Title:    title,
+			Subtitle: correlationSummary(x, y),
 		}),
 		charts.WithXAxisOpts(opts.XAxis{
 			Type: "value",
-			Name: strings.ReplaceAll(metricKey, "_", " "),
+			Name: xLabel,
 		}),
 		charts.WithYAxisOpts(opts.YAxis{
 			Type: "value",
-			Name: strings.ReplaceAll(symptomKey, "_", " "),
+			Name: yLabel,
 		}),
 		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
 	)
 
 	items := make([]opts.ScatterData, 0)
 	for _, point := range data {
-		items = append(items, opts.ScatterData{Value: []interface{}{point.MetricValue, point.SymptomValue}})
+		items = append(items, opts.ScatterData{Value: []interface{}{point.X, point.Y}})
 	}
 
 	scatter.AddSeries("Correlation", items)
+
+	// The least-squares line is drawn across the range of the task metric.
+	if fit, err := stats.LinearFit(x, y); err == nil {
+		low, high := slices.Min(x), slices.Max(x)
+		scatter.MultiSeries = append(scatter.MultiSeries, charts.SingleSeries{
+			Name: "Regression line",
+			Type: types.ChartLine,
+			Data: []opts.LineData{
+				{Value: []interface{}{low, fit.Predict(low)}},
+				{Value: []interface{}{high, fit.Predict(high)}},
+			},
+			ShowSymbol: opts.Bool(false),
+			LineStyle:  &opts.LineStyle{Width: 2, Color: "#ea580c"},
+			ItemStyle:  &opts.ItemStyle{Color: "#ea580c"},
+		})
+	}
 	return scatter
 }
+
+// correlationSummary reports the Pearson and Spearman coefficients of the pairs
+// with their p-values, or why they cannot be computed.
+func correlationSummary(x, y []float64) string {
+	pearson, err := stats.Pearson(x, y)
+	switch {
+	case errors.Is(err, stats.ErrTooFewPoints):
+		return fmt.Sprintf("n = %d: at least 3 pairs are needed for a correlation", len(x))
+	case err != nil:
+		return fmt.Sprintf("n = %d: one of the values never changes", len(x))
+	}
+	spearman, _ := stats.Spearman(x, y)
+	return fmt.Sprintf("Pearson r = %.2f (p %s) · Spearman ρ = %.2f (p %s) · n = %d",
+		pearson.R, formatP(pearson.P), spearman.R, formatP(spearman.P), pearson.N)
+}
+
+func formatP(p float64) string {
+	if p < 0.001 {
+		return "< 0.001"
+	}
+	return fmt.Sprintf("= %.3f", p)
+}
+
+func pluralDays(n int) string {
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
+
+// generateOverlayChart draws several series on one time axis, either each on its
+// own y-axis or all as z-scores of their own values. Only the first two axes are
+// labelled, one on each side; the tooltip shows every series' raw value.
+func generateOverlayChart(selection []models.MetricOption, series map[repository.SeriesKey][]repository.TimelineDataPoint, mode string) *charts.Line {
+	yAxis := opts.YAxis{Type: "value", Scale: opts.Bool(true)}
+	subtitle := "Each series on its own axis"
+	if mode == overlayNormalized {
+		yAxis.Name = "z-score"
+		subtitle = "Each series as z-scores of its own values"
+	}
+
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{Title: "Metrics Over Time", Subtitle: subtitle}),
+		charts.WithXAxisOpts(opts.XAxis{Type: "time"}),
+		charts.WithYAxisOpts(yAxis),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Bottom: "40"}),
+		charts.WithGridOpts(opts.Grid{Bottom: "100"}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true), Trigger: "axis"}),
+		charts.WithDataZoomOpts(opts.DataZoom{Type: "slider"}),
+	)
+
+	for i, option := range selection {
+		key, _ := repository.ParseSeriesKey(option.Value)
+		points := series[key]
+
+		values := make([]float64, len(points))
+		for j, point := range points {
+			values[j] = point.Value
+		}
+		mean, sd := stats.Mean(values), stats.StdDev(values)
+
+		items := make([]opts.LineData, 0, len(points))
+		for _, point := range points {
+			value := point.Value
+			if mode == overlayNormalized {
+				value = 0
+				if sd > 0 {
+					value = (point.Value - mean) / sd
+				}
+			}
+			items = append(items, opts.LineData{Value: []interface{}{point.Date, value}})
+		}
+
+		axisIndex := 0
+		if mode == overlayAxes {
+			axisIndex = i
+			if i > 0 {
+				position := "left"
+				if i%2 == 1 {
+					position = "right"
+				}
+				line.ExtendYAxis(opts.YAxis{
+					Type:      "value",
+					Scale:     opts.Bool(true),
+					Position:  position,
+					Show:      opts.Bool(i < 2),
+					SplitLine: &opts.SplitLine{Show: opts.Bool(false)},
+				})
+			}
+		}
+		line.AddSeries(option.Label, items, charts.WithLineChartOpts(opts.LineChart{YAxisIndex: axisIndex}))
+	}
+	return line
+}
+
+// generateCorrelationMatrixChart draws the Pearson correlations as a heatmap from
+// -1 (blue) to 1 (red). Pairs that cannot be correlated are left blank.
+func generateCorrelationMatrixChart(series []models.MetricOption, matrix [][]*stats.Correlation) *charts.HeatMap {
+	labels := make([]string, len(series))
+	for i, option := range series {
+		labels[i] = option.Label
+	}
+
+	heatmap := charts.NewHeatMap()
+	heatmap.SetGlobalOptions(
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Correlation Matrix",
+			Subtitle: "Pearson r between every pair of series. Click a cell to open its scatter plot.",
+		}),
+		charts.WithGridOpts(opts.Grid{ContainLabel: opts.Bool(true), Top: "80", Bottom: "60"}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Type:      "category",
+			AxisLabel: &opts.AxisLabel{Rotate: 60, Interval: "0"},
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Type:      "category",
+			Data:      labels,
+			AxisLabel: &opts.AxisLabel{Interval: "0"},
+		}),
+		charts.WithVisualMapOpts(opts.VisualMap{
+			Calculable: opts.Bool(true),
+			Min:        -1,
+			Max:        1,
+			Orient:     "horizontal",
+			Left:       "center",
+			Bottom:     "0",
+			InRange:    &opts.VisualMapInRange{Color: []string{"#2563eb", "#f5f5f5", "#dc2626"}},
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+	)
+	heatmap.SetXAxis(labels)
+
+	cells := make([]opts.HeatMapData, 0, len(series)*len(series))
+	for i, row := range matrix {
+		for j, r := range row {
+			if r == nil {
+				continue
+			}
+			cells = append(cells, opts.HeatMapData{Value: [3]interface{}{i, j, math.Round(r.R*100) / 100}})
+		}
+	}
+	heatmap.AddSeries("Pearson r", cells)
+	return heatmap
+}
@@ -6,13 +6,17 @@ import (
 	"crapp-go/views"
 	"crapp-go/views/components"
 	"crapp-go/views/profile"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/a-h/templ"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type UserHandler struct {
@@ -77,7 +81,13 @@ func (h *UserHandler) UpdateInfo(c *gin.Context) {
 	firstName := c.PostForm("first_name")
 	lastName := c.PostForm("last_name")
 
-	if err := repository.UpdateUser(c, userID, firstName, lastName); err != nil {
+	demographics, err := parseDemographics(c)
+	if err != nil {
+		components.Alert(err.Error(), "error").Render(c, c.Writer)
+		return
+	}
+
+	if err := repository.UpdateUser(c, userID, firstName, lastName, demographics); err != nil {
 		h.log.Error("Failed to update user info", zap.Error(err), zap.Uint("userID", userID))
 		components.Alert("Failed to update profile", "error").Render(c, c.Writer)
 		return
@@ -168,3 +178,105 @@ func (h *UserHandler) DeleteAccount(c *gin.Context) {
 	}
 	c.Header("HX-Redirect", "/")
 }
+
+// ShowClinicianAccess lists the clinicians the user has added and the patients
+// who have added them.
+func (h *UserHandler) ShowClinicianAccess(c *gin.Context) {
+	h.renderClinicianAccess(c, "", "")
+}
+
+// AddClinician grants the user with the posted email access to the signed-in
+// user's assessments.
+func (h *UserHandler) AddClinician(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(*models.User)
+	email := strings.TrimSpace(c.PostForm("email"))
+
+	clinician, err := repository.GetUserByEmail(c, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		h.renderClinicianAccess(c, "No account uses that email address.", "error")
+		return
+	}
+	if err != nil {
+		h.log.Error("Failed to look up clinician", zap.Error(err), zap.Uint("userID", currentUser.ID))
+		h.renderClinicianAccess(c, "Failed to add clinician.", "error")
+		return
+	}
+	if clinician.ID == currentUser.ID {
+		h.renderClinicianAccess(c, "You already have access to your own assessments.", "warning")
+		return
+	}
+	if err := repository.GrantClinicianAccess(c, currentUser.ID, clinician.ID); err != nil {
+		h.log.Error("Failed to grant clinician access", zap.Error(err), zap.Uint("userID", currentUser.ID), zap.Uint("clinicianID", clinician.ID))
+		h.renderClinicianAccess(c, "Failed to add clinician.", "error")
+		return
+	}
+	h.log.Info("Clinician access granted", zap.Uint("userID", currentUser.ID), zap.Uint("clinicianID", clinician.ID))
+	h.renderClinicianAccess(c, "Clinician added.", "success")
+}
+
+// RemoveClinician revokes a clinician's access to the signed-in user's
+// assessments.
+func (h *UserHandler) RemoveClinician(c *gin.Context) {
+	user, _ := c.Get("user")
+	currentUser := user.(*models.User)
+	clinicianID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid clinician")
+		return
+	}
+	if err := repository.RevokeClinicianAccess(c, currentUser.ID, uint(clinicianID)); err != nil {
+		h.log.Error("Failed to revoke clinician access", zap.Error(err), zap.Uint("userID", currentUser.ID), zap.Uint64("clinicianID", clinicianID))
+		h.renderClinicianAccess(c, "Failed to remove clinician.", "error")
+		return
+	}
+	h.log.Info("Clinician access revoked", zap.Uint("userID", currentUser.ID), zap.Uint64("clinicianID", clinicianID))
+	h.renderClinicianAccess(c, "Clinician removed.", "success")
+}
+
+func (h *UserHandler) renderClinicianAccess(c *gin.Context, message, messageType string) {
+	user, _ := c.Get("user")
+	currentUser := user.(*models.User)
+	csrfToken, _ := c.Get("csrf_token")
+
+	clinicians, err := repository.GetClinicians(c, currentUser.ID)
+	if err != nil {
+		h.log.Error("Failed to get clinicians", zap.Error(err), zap.Uint("userID", currentUser.ID))
+		components.Alert("Failed to load clinicians.", "error").Render(c, c.Writer)
+		return
+	}
+	patients, err := repository.GetPatients(c, currentUser.ID)
+	if err != nil {
+		h.log.Error("Failed to get patients", zap.Error(err), zap.Uint("userID", currentUser.ID))
+		components.Alert("Failed to load clinicians.", "error").Render(c, c.Writer)
+		return
+	}
+	profile.ClinicianAccess(clinicians, patients, csrfToken.(string), message, messageType).Render(c, c.Writer)
+}
+
+// parseDemographics reads the optional demographic fields of the personal
+// information form. Empty fields are left unknown.
+func parseDemographics(c *gin.Context) (models.Demographics, error) {
+	var d models.Demographics
+	if v := c.PostForm("date_of_birth"); v != "" {
+		dob, err := time.Parse("2006-01-02", v)
+		if err != nil || dob.After(time.Now()) {
+			return d, errors.New("Please enter a valid date of birth")
+		}
+		d.DateOfBirth = &dob
+	}
+	if v := c.PostForm("education_years"); v != "" {
+		years, err := strconv.Atoi(v)
+		if err != nil || years < 0 || years > 40 {
+			return d, errors.New("Please enter your years of education as a number between 0 and 40")
+		}
+		d.EducationYears = &years
+	}
+	switch sex := c.PostForm("sex"); sex {
+	case "", models.SexFemale, models.SexMale:
+		d.Sex = sex
+	default:
+		return d, errors.New("Please choose a valid option for sex")
+	}
+	return d, nil
+}
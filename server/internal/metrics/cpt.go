@@ -8,6 +8,7 @@ type CPTStimulusPresentation struct {
 	Value       string  `json:"value"`
 	IsTarget    bool    `json:"isTarget"`
 	PresentedAt float64 `json:"presentedAt"`
+	IsPractice  bool    `json:"isPractice"`
 }
 
 type CPTResponse struct {
@@ -15,6 +16,7 @@ type CPTResponse struct {
 	IsTarget      bool    `json:"isTarget"`
 	ResponseTime  float64 `json:"responseTime"`
 	StimulusIndex int     `json:"stimulusIndex"`
+	IsPractice    bool    `json:"isPractice"`
 }
 
 // CPTData represents the structure of raw CPT test data
@@ -24,12 +26,35 @@ type CPTData struct {
 	StimuliPresented []CPTStimulusPresentation `json:"stimuliPresented"`
 	Responses        []CPTResponse             `json:"responses"`
 	Settings         map[string]any            `json:"settings"`
+	FocusData
+}
+
+// scoredStimuli returns the stimuli that count toward the score, skipping practice trials.
+func scoredStimuli(data *CPTData) []CPTStimulusPresentation {
+	stimuli := make([]CPTStimulusPresentation, 0, len(data.StimuliPresented))
+	for _, stim := range data.StimuliPresented {
+		if !stim.IsPractice {
+			stimuli = append(stimuli, stim)
+		}
+	}
+	return stimuli
+}
+
+// scoredResponses returns the responses that count toward the score, skipping practice trials.
+func scoredResponses(data *CPTData) []CPTResponse {
+	responses := make([]CPTResponse, 0, len(data.Responses))
+	for _, response := range data.Responses {
+		if !response.IsPractice {
+			responses = append(responses, response)
+		}
+	}
+	return responses
 }
 
 // Helper methods for CPT calculations
 func CountCorrectDetections(data *CPTData) int {
 	count := 0
-	for _, response := range data.Responses {
+	for _, response := range scoredResponses(data) {
 		if response.IsTarget {
 			count++
 		}
@@ -39,7 +64,7 @@ func CountCorrectDetections(data *CPTData) int {
 
 func CountCommissionErrors(data *CPTData) int {
 	count := 0
-	for _, response := range data.Responses {
+	for _, response := range scoredResponses(data) {
 		if !response.IsTarget {
 			count++
 		}
@@ -50,7 +75,7 @@ func CountCommissionErrors(data *CPTData) int {
 func CountOmissionErrors(data *CPTData) int {
 	// Count total targets presented
 	totalTargets := 0
-	for _, stim := range data.StimuliPresented {
+	for _, stim := range scoredStimuli(data) {
 		if stim.IsTarget {
 			totalTargets++
 		}
@@ -64,7 +89,7 @@ func CalculateAverageReactionTime(data *CPTData) float64 {
 	var sum float64
 	var count int
 
-	for _, response := range data.Responses {
+	for _, response := range scoredResponses(data) {
 		if response.IsTarget {
 			sum += response.ResponseTime
 			count++
@@ -80,7 +105,7 @@ func CalculateAverageReactionTime(data *CPTData) float64 {
 func CalculateReactionTimeSD(data *CPTData) float64 {
 	// Get reaction times and average
 	var reactionTimes []float64
-	for _, response := range data.Responses {
+	for _, response := range scoredResponses(data) {
 		if response.IsTarget {
 			reactionTimes = append(reactionTimes, response.ResponseTime)
 		}
@@ -105,7 +130,7 @@ func CalculateReactionTimeSD(data *CPTData) float64 {
 func CalculateDetectionRate(data *CPTData) float64 {
 	// Count total targets presented
 	totalTargets := 0
-	for _, stim := range data.StimuliPresented {
+	for _, stim := range scoredStimuli(data) {
 		if stim.IsTarget {
 			totalTargets++
 		}
@@ -121,7 +146,7 @@ func CalculateDetectionRate(data *CPTData) float64 {
 func CalculateOmissionErrorRate(data *CPTData) float64 {
 	// Count total targets presented
 	totalTargets := 0
-	for _, stim := range data.StimuliPresented {
+	for _, stim := range scoredStimuli(data) {
 		if stim.IsTarget {
 			totalTargets++
 		}
@@ -137,7 +162,7 @@ func CalculateOmissionErrorRate(data *CPTData) float64 {
 func CalculateCommissionErrorRate(data *CPTData) float64 {
 	// Count non-targets
 	nonTargetCount := 0
-	for _, stim := range data.StimuliPresented {
+	for _, stim := range scoredStimuli(data) {
 		if !stim.IsTarget {
 			nonTargetCount++
 		}
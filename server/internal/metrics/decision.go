@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+
+	"crapp-go/internal/models"
+)
+
+// idleThreshold is the shortest gap in activity, in milliseconds, counted as an idle period.
+const idleThreshold = 2000.0
+
+// questionView is one stretch of time a question was on screen.
+type questionView struct {
+	start, end float64
+}
+
+// calculateDecisionMetrics calculates how the user arrived at an answer: how long
+// before they first moved and clicked, how often they changed their selection,
+// how long they hovered over each option, and how often they sat idle. Views of
+// the question (a user who goes back sees it again) are scored separately and
+// averaged. Only question buckets with recorded views are scored.
+func calculateDecisionMetrics(questionID *string, interactions *models.InteractionData) map[string]MetricResult {
+	metrics := map[string]MetricResult{
+		"time_to_first_movement": {Value: 0.0, Calculated: false, SampleSize: 0},
+		"time_to_first_click":    {Value: 0.0, Calculated: false, SampleSize: 0},
+		"option_switches":        {Value: 0.0, Calculated: false, SampleSize: 0},
+		"option_hover_time":      {Value: 0.0, Calculated: false, SampleSize: 0},
+		"idle_period_count":      {Value: 0.0, Calculated: false, SampleSize: 0},
+		"idle_time":              {Value: 0.0, Calculated: false, SampleSize: 0},
+	}
+	if questionID == nil {
+		return metrics
+	}
+
+	views := questionViews(filterPageEventsByQuestion(questionID, interactions))
+	if len(views) == 0 {
+		return metrics
+	}
+
+	movements := filterMovementsByQuestion(questionID, interactions)
+	clicks := filterInteractionsByQuestion(questionID, interactions)
+	keys := filterKeyboardEventsByQuestion(questionID, interactions)
+	gestures := filterGesturesByQuestion(questionID, interactions)
+	selections := make([]models.PageEvent, 0)
+	for _, e := range filterPageEventsByQuestion(questionID, interactions) {
+		if e.Type == models.PageOptionSelected {
+			selections = append(selections, e)
+		}
+	}
+
+	var firstMovement, firstClick, switches, hover, idleCount, idleTime []float64
+	for _, v := range views {
+		in := func(t float64) bool { return t >= v.start && t <= v.end }
+
+		// Time to first movement: the first pointer movement or touch
+		first := math.Inf(1)
+		for _, m := range movements {
+			if in(m.Timestamp) {
+				first = math.Min(first, m.Timestamp)
+			}
+		}
+		for _, g := range gestures {
+			if in(g.Timestamp) {
+				first = math.Min(first, g.Timestamp)
+			}
+		}
+		if !math.IsInf(first, 1) {
+			firstMovement = append(firstMovement, first-v.start)
+		}
+
+		// Time to first click
+		first = math.Inf(1)
+		for _, c := range clicks {
+			if in(c.Timestamp) {
+				first = math.Min(first, c.Timestamp)
+			}
+		}
+		if !math.IsInf(first, 1) {
+			firstClick = append(firstClick, first-v.start)
+		}
+
+		// Option switches: selections of a different option after the first choice
+		selected := ""
+		viewSwitches := 0
+		for _, s := range selections {
+			if !in(s.Timestamp) {
+				continue
+			}
+			if selected != "" && s.TargetID != selected {
+				viewSwitches++
+			}
+			selected = s.TargetID
+		}
+		if selected != "" {
+			switches = append(switches, float64(viewSwitches))
+		}
+
+		// Hover time per option: each movement sample's target is assumed to stay
+		// under the pointer until the next sample (or the end of the view).
+		hoverByTarget := make(map[string]float64)
+		var inView []models.MouseMovement
+		for _, m := range movements {
+			if in(m.Timestamp) {
+				inView = append(inView, m)
+			}
+		}
+		sort.Slice(inView, func(i, j int) bool { return inView[i].Timestamp < inView[j].Timestamp })
+		for i, m := range inView {
+			if m.TargetID == "" {
+				continue
+			}
+			next := v.end
+			if i+1 < len(inView) {
+				next = inView[i+1].Timestamp
+			}
+			hoverByTarget[m.TargetID] += next - m.Timestamp
+		}
+		if len(hoverByTarget) > 0 {
+			total := 0.0
+			for _, t := range hoverByTarget {
+				total += t
+			}
+			hover = append(hover, total/float64(len(hoverByTarget)))
+		}
+
+		// Idle periods: gaps of at least idleThreshold with no activity at all
+		activity := []float64{v.start, v.end}
+		for _, m := range inView {
+			activity = append(activity, m.Timestamp)
+		}
+		for _, c := range clicks {
+			if in(c.Timestamp) {
+				activity = append(activity, c.Timestamp)
+			}
+		}
+		for _, k := range keys {
+			if in(k.Timestamp) {
+				activity = append(activity, k.Timestamp)
+			}
+		}
+		for _, g := range gestures {
+			if in(g.Timestamp) {
+				activity = append(activity, g.Timestamp, g.Timestamp+g.Duration)
+			}
+		}
+		for _, s := range selections {
+			if in(s.Timestamp) {
+				activity = append(activity, s.Timestamp)
+			}
+		}
+		sort.Float64s(activity)
+		periods, idle := 0, 0.0
+		for i := 1; i < len(activity); i++ {
+			if gap := activity[i] - activity[i-1]; gap >= idleThreshold {
+				periods++
+				idle += gap
+			}
+		}
+		idleCount = append(idleCount, float64(periods))
+		idleTime = append(idleTime, idle)
+	}
+
+	set := func(key string, values []float64) {
+		if len(values) == 0 {
+			return
+		}
+		sum := 0.0
+		for _, v := range values {
+			sum += v
+		}
+		metrics[key] = MetricResult{Value: sum / float64(len(values)), Calculated: true, SampleSize: len(values)}
+	}
+	set("time_to_first_movement", firstMovement)
+	set("time_to_first_click", firstClick)
+	set("option_switches", switches)
+	set("option_hover_time", hover)
+	set("idle_period_count", idleCount)
+	set("idle_time", idleTime)
+
+	return metrics
+}
+
+// questionViews pairs each question_shown event with the question_left event that
+// follows it. A view that was never closed (e.g. the tab was closed) is dropped,
+// since its end is unknown.
+func questionViews(events []models.PageEvent) []questionView {
+	sorted := make([]models.PageEvent, len(events))
+	copy(sorted, events)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+
+	views := make([]questionView, 0)
+	open := false
+	var start float64
+	for _, e := range sorted {
+		switch e.Type {
+		case models.PageQuestionShown:
+			open = true
+			start = e.Timestamp
+		case models.PageQuestionLeft:
+			if open && e.Timestamp > start {
+				views = append(views, questionView{start: start, end: e.Timestamp})
+			}
+			open = false
+		}
+	}
+	return views
+}
+
+func filterPageEventsByQuestion(questionID *string, interactions *models.InteractionData) []models.PageEvent {
+	if questionID == nil {
+		return interactions.PageEvents
+	}
+
+	filtered := make([]models.PageEvent, 0)
+	for _, event := range interactions.PageEvents {
+		if event.QuestionID == *questionID {
+			filtered = append(filtered, event)
+		}
+	}
+
+	return filtered
+}
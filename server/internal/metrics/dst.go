@@ -5,12 +5,13 @@ import (
 )
 
 type DigitSpanAttempt struct {
-	Span      int     `json:"span"`
-	Trial     int     `json:"trial"`
-	Sequence  string  `json:"sequence"`
-	Input     string  `json:"input"`
-	Correct   bool    `json:"correct"`
-	Timestamp float64 `json:"timestamp"` // Relative timestamp from test start
+	Span       int     `json:"span"`
+	Trial      int     `json:"trial"`
+	Sequence   string  `json:"sequence"`
+	Input      string  `json:"input"`
+	Correct    bool    `json:"correct"`
+	Timestamp  float64 `json:"timestamp"`  // Relative timestamp from test start
+	IsPractice bool    `json:"isPractice"` // Practice attempts are never scored
 }
 
 type DigitSpanRawData struct {
@@ -18,12 +19,13 @@ type DigitSpanRawData struct {
 	TestEndTime   float64            `json:"testEndTime"`   // JS performance.now() timestamp
 	Results       []DigitSpanAttempt `json:"results"`       // Array of attempt data
 	Settings      map[string]any     `json:"settings"`      // Test settings used
+	FocusData
 }
 
 func CalculateDigitSpanMetrics(results *DigitSpanRawData) (*models.DSTResult, error) {
 	// --- Calculate Metrics ---
 	highestSpan := 0
-	totalTrials := len(results.Results)
+	totalTrials := 0
 	correctTrials := 0
 	initialSpan := 3 // Default
 
@@ -39,6 +41,10 @@ func CalculateDigitSpanMetrics(results *DigitSpanRawData) (*models.DSTResult, er
 	minAttemptedSpan := initialSpan // Track the lowest span actually attempted
 
 	for _, attempt := range results.Results {
+		if attempt.IsPractice {
+			continue
+		}
+		totalTrials++
 		if attempt.Span < minAttemptedSpan {
 			minAttemptedSpan = attempt.Span
 		}
@@ -0,0 +1,18 @@
+package metrics
+
+// FocusData records how often a cognitive test lost focus while it was running.
+// It is embedded in each test's raw payload.
+type FocusData struct {
+	FocusLostCount int     `json:"focusLostCount"`
+	TimeHidden     float64 `json:"timeHidden"` // Milliseconds the tab was hidden
+}
+
+// Facts returns the focus values in the form used by validity rules. They share
+// their names with the interruption metrics computed from the interaction data,
+// so one rule covers both sources.
+func (f FocusData) Facts() map[string]float64 {
+	return map[string]float64{
+		"interruption_count": float64(f.FocusLostCount),
+		"time_away":          f.TimeHidden,
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"sort"
+
+	"crapp-go/internal/models"
+)
+
+// calculateInterruptionMetrics calculates how often the user left the page and for
+// how long. The user is away from the moment the tab is hidden or the window loses
+// focus until it is both visible and focused again; overlapping blur and hide
+// events count as a single interruption. The metrics are only calculated for
+// buckets where the tracker recorded the page's visibility or focus, since older
+// clients did not record them and would otherwise look uninterrupted.
+func calculateInterruptionMetrics(questionID *string, interactions *models.InteractionData) map[string]MetricResult {
+	metrics := map[string]MetricResult{
+		"interruption_count": {Value: 0.0, Calculated: false, SampleSize: 0},
+		"time_away":          {Value: 0.0, Calculated: false, SampleSize: 0},
+	}
+
+	events := filterPageEventsByQuestion(questionID, interactions)
+	sorted := make([]models.PageEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+
+	stateEvents := 0
+	hidden, blurred := false, false
+	interruptions := 0
+	timeAway, awaySince := 0.0, 0.0
+	counting := false
+	for _, e := range sorted {
+		wasAway := hidden || blurred
+		switch e.Type {
+		case models.PageHidden:
+			hidden = true
+		case models.PageVisible:
+			hidden = false
+		case models.PageBlur:
+			blurred = true
+		case models.PageFocus:
+			blurred = false
+		default:
+			// Not a visibility or focus change
+			if counting && e.Type == models.PageQuestionLeft {
+				// Leaving the question ends its share of the interruption.
+				timeAway += e.Timestamp - awaySince
+				counting = false
+			}
+			continue
+		}
+		stateEvents++
+
+		away := hidden || blurred
+		if away && !wasAway {
+			interruptions++
+			awaySince = e.Timestamp
+			counting = true
+		} else if !away && counting {
+			timeAway += e.Timestamp - awaySince
+			counting = false
+		}
+	}
+	if stateEvents == 0 {
+		return metrics
+	}
+
+	metrics["interruption_count"] = MetricResult{Value: float64(interruptions), Calculated: true, SampleSize: stateEvents}
+	metrics["time_away"] = MetricResult{Value: timeAway, Calculated: true, SampleSize: stateEvents}
+	return metrics
+}
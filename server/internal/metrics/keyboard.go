@@ -3,11 +3,79 @@ package metrics
 import (
 	"math"
 	"sort"
+	"strconv"
+	"unicode/utf8"
 
 	"crapp-go/internal/models"
 )
 
-// calculateKeyboardMetrics calculates all keyboard-related metrics with enhanced analysis
+// KeyCategory reduces a KeyboardEvent.key value to its category.
+func KeyCategory(key string) string {
+	switch key {
+	case " ", "Spacebar", "Space", "Enter":
+		return models.KeyCategorySpace
+	case "Backspace":
+		return models.KeyCategoryBackspace
+	case "Delete":
+		return models.KeyCategoryDelete
+	case "ArrowLeft", "ArrowRight", "ArrowUp", "ArrowDown", "Home", "End", "PageUp", "PageDown", "Tab":
+		return models.KeyCategoryNavigation
+	case "Shift", "Control", "Alt", "AltGraph", "Meta", "CapsLock", "Fn":
+		return models.KeyCategoryModifier
+	}
+	if utf8.RuneCountInString(key) == 1 {
+		return models.KeyCategoryCharacter
+	}
+	return models.KeyCategoryOther
+}
+
+// RedactKeys replaces the key of every event with its category, so that only
+// categories are stored or logged.
+func RedactKeys(events []models.KeyboardEvent) {
+	for i := range events {
+		if events[i].Category == "" {
+			events[i].Category = KeyCategory(events[i].Key)
+		}
+		events[i].Key = ""
+	}
+}
+
+// keyCategory returns an event's category, deriving it from the key for data
+// captured before categories were recorded.
+func keyCategory(event models.KeyboardEvent) string {
+	if event.Category != "" {
+		return event.Category
+	}
+	return KeyCategory(event.Key)
+}
+
+// isContentKey reports whether a key adds text: a character, space or Enter.
+func isContentKey(event models.KeyboardEvent) bool {
+	category := keyCategory(event)
+	return category == models.KeyCategoryCharacter || category == models.KeyCategorySpace
+}
+
+// isCorrectionKey reports whether a key removes text.
+func isCorrectionKey(event models.KeyboardEvent) bool {
+	category := keyCategory(event)
+	return category == models.KeyCategoryBackspace || category == models.KeyCategoryDelete
+}
+
+// pressKey identifies the physical key press an event belongs to, so keyups can
+// be matched to keydowns. Category-only data is matched by press ID.
+func pressKey(event models.KeyboardEvent) string {
+	if event.PressID != 0 {
+		return "press:" + strconv.Itoa(event.PressID)
+	}
+	if event.Key != "" {
+		return "key:" + event.Key
+	}
+	return "category:" + keyCategory(event)
+}
+
+// calculateKeyboardMetrics calculates all keyboard-related metrics with enhanced analysis.
+// Only key categories are used, so the metrics are the same whether keystrokes were
+// captured raw or as categories.
 func calculateKeyboardMetrics(questionID *string, interactions *models.InteractionData) map[string]MetricResult {
 	events := filterKeyboardEventsByQuestion(questionID, interactions)
 	metrics := make(map[string]MetricResult)
@@ -42,7 +110,7 @@ func calculateKeyboardMetrics(questionID *string, interactions *models.Interacti
 	if len(keydownEvents) >= 5 {
 		contentKeys := 0
 		for _, event := range keydownEvents {
-			if len(event.Key) == 1 || event.Key == "Space" || event.Key == "Enter" {
+			if isContentKey(event) {
 				contentKeys++
 			}
 		}
@@ -123,16 +191,17 @@ func calculateKeyboardMetrics(questionID *string, interactions *models.Interacti
 	keyDownMap := make(map[string]float64)
 
 	for _, event := range events {
+		key := pressKey(event)
 		switch event.Type {
 		case "keydown":
-			keyDownMap[event.Key] = event.Timestamp
+			keyDownMap[key] = event.Timestamp
 		case "keyup":
-			if downTime, exists := keyDownMap[event.Key]; exists {
+			if downTime, exists := keyDownMap[key]; exists {
 				holdTime := event.Timestamp - downTime
 				if holdTime >= 20 && holdTime <= 1000 {
 					keyHoldTimes = append(keyHoldTimes, holdTime)
 				}
-				delete(keyDownMap, event.Key)
+				delete(keyDownMap, key)
 			}
 		}
 	}
@@ -177,13 +246,13 @@ func calculateKeyboardMetrics(questionID *string, interactions *models.Interacti
 		charCount := 0
 
 		for i, event := range keydownEvents {
-			if event.Key == "Backspace" || event.Key == "Delete" {
+			if isCorrectionKey(event) {
 				correctionCount++
 				if lastCorrection >= 0 && i-lastCorrection <= 3 {
 					immediateCorrections++
 				}
 				lastCorrection = i
-			} else if len(event.Key) == 1 || event.Key == "Space" || event.Key == "Enter" {
+			} else if isContentKey(event) {
 				charCount++
 			}
 		}
@@ -0,0 +1,254 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+
+	"crapp-go/internal/models"
+)
+
+// reach is one aimed mouse movement: the samples recorded while heading for a
+// target, ending with the click on it.
+type reach struct {
+	samples []models.MouseMovement
+	click   models.MouseInteraction
+}
+
+// duration returns the movement time of the reach in milliseconds.
+func (r reach) duration() float64 {
+	return r.samples[len(r.samples)-1].Timestamp - r.samples[0].Timestamp
+}
+
+// distance returns the straight-line distance from the start of the reach to the click.
+func (r reach) distance() float64 {
+	first, last := r.samples[0], r.samples[len(r.samples)-1]
+	return math.Hypot(last.X-first.X, last.Y-first.Y)
+}
+
+// speeds returns the speed between consecutive samples in pixels per second, with
+// the time of each speed taken at the midpoint of its interval.
+func (r reach) speeds() (times, speeds []float64) {
+	for i := 1; i < len(r.samples); i++ {
+		dt := r.samples[i].Timestamp - r.samples[i-1].Timestamp
+		if dt <= 0 {
+			continue
+		}
+		dist := math.Hypot(r.samples[i].X-r.samples[i-1].X, r.samples[i].Y-r.samples[i-1].Y)
+		times = append(times, (r.samples[i].Timestamp+r.samples[i-1].Timestamp)/2)
+		speeds = append(speeds, dist/(dt/1000))
+	}
+	return times, speeds
+}
+
+// extractReaches pairs each click with the movements made towards its target.
+// Reaches shorter than 10 pixels or with fewer than minSamples samples (including
+// the click) are skipped, as their kinematics are dominated by noise.
+func extractReaches(questionID *string, interactions *models.InteractionData, minSamples int) []reach {
+	movements := filterMovementsByQuestion(questionID, interactions)
+	inter := filterInteractionsByQuestion(questionID, interactions)
+
+	targetMovements := make(map[string][]models.MouseMovement)
+	for _, movement := range movements {
+		if movement.TargetID != "" {
+			targetMovements[movement.TargetID] = append(targetMovements[movement.TargetID], movement)
+		}
+	}
+
+	reaches := make([]reach, 0, len(inter))
+	for _, click := range inter {
+		samples := make([]models.MouseMovement, 0, len(targetMovements[click.TargetID])+1)
+		for _, m := range targetMovements[click.TargetID] {
+			if m.Timestamp < click.Timestamp {
+				samples = append(samples, m)
+			}
+		}
+		sort.Slice(samples, func(i, j int) bool {
+			return samples[i].Timestamp < samples[j].Timestamp
+		})
+		samples = append(samples, models.MouseMovement{X: click.ClickX, Y: click.ClickY, Timestamp: click.Timestamp})
+
+		r := reach{samples: samples, click: click}
+		if len(samples) < minSamples || r.duration() <= 0 || r.distance() < 10.0 {
+			continue
+		}
+		reaches = append(reaches, r)
+	}
+	return reaches
+}
+
+// calculatePeakVelocityTime returns when peak velocity is reached, as a fraction of
+// movement time (0-1). Healthy aimed movements peak a little before halfway;
+// later peaks indicate a cautious, feedback-driven approach.
+func calculatePeakVelocityTime(questionID *string, interactions *models.InteractionData) MetricResult {
+	reaches := extractReaches(questionID, interactions, 3)
+
+	sum := 0.0
+	for _, r := range reaches {
+		times, speeds := r.speeds()
+		peak := 0
+		for i := range speeds {
+			if speeds[i] > speeds[peak] {
+				peak = i
+			}
+		}
+		sum += (times[peak] - r.samples[0].Timestamp) / r.duration()
+	}
+
+	if len(reaches) == 0 {
+		return MetricResult{Value: 0.0, Calculated: false, SampleSize: 0}
+	}
+	return MetricResult{Value: sum / float64(len(reaches)), Calculated: true, SampleSize: len(reaches)}
+}
+
+// calculateNormalizedJerk returns the dimensionless normalised jerk of aimed
+// movements: sqrt(0.5 * integral(jerk^2) * T^5 / L^2). It does not depend on
+// movement size or duration, and lower values mean smoother movement.
+func calculateNormalizedJerk(questionID *string, interactions *models.InteractionData) MetricResult {
+	reaches := extractReaches(questionID, interactions, 5)
+
+	sum := 0.0
+	count := 0
+	for _, r := range reaches {
+		times, speeds := r.speeds()
+		if len(speeds) < 4 {
+			continue
+		}
+
+		// Differentiate speed twice to get jerk (pixels per second cubed).
+		accelTimes, accels := differentiate(times, speeds)
+		jerkTimes, jerks := differentiate(accelTimes, accels)
+		if len(jerks) < 2 {
+			continue
+		}
+
+		integral := 0.0
+		for i := 1; i < len(jerks); i++ {
+			dt := (jerkTimes[i] - jerkTimes[i-1]) / 1000
+			integral += (jerks[i]*jerks[i] + jerks[i-1]*jerks[i-1]) / 2 * dt
+		}
+
+		duration := r.duration() / 1000
+		length := r.distance()
+		sum += math.Sqrt(0.5 * integral * math.Pow(duration, 5) / (length * length))
+		count++
+	}
+
+	if count == 0 {
+		return MetricResult{Value: 0.0, Calculated: false, SampleSize: 0}
+	}
+	return MetricResult{Value: sum / float64(count), Calculated: true, SampleSize: count}
+}
+
+// differentiate returns the finite-difference derivative of values over times
+// (in milliseconds), per second.
+func differentiate(times, values []float64) (outTimes, derivative []float64) {
+	for i := 1; i < len(values); i++ {
+		dt := (times[i] - times[i-1]) / 1000
+		if dt <= 0 {
+			continue
+		}
+		outTimes = append(outTimes, (times[i]+times[i-1])/2)
+		derivative = append(derivative, (values[i]-values[i-1])/dt)
+	}
+	return outTimes, derivative
+}
+
+// calculateCurvatureIndex returns the largest perpendicular deviation of the path
+// from the straight line between its start and the click, divided by the length of
+// that line. A straight reach scores 0.
+func calculateCurvatureIndex(questionID *string, interactions *models.InteractionData) MetricResult {
+	reaches := extractReaches(questionID, interactions, 3)
+
+	sum := 0.0
+	for _, r := range reaches {
+		first, last := r.samples[0], r.samples[len(r.samples)-1]
+		length := r.distance()
+
+		maxDeviation := 0.0
+		for _, s := range r.samples[1 : len(r.samples)-1] {
+			// Distance from the sample to the start-click line (cross product / length)
+			deviation := math.Abs((last.X-first.X)*(first.Y-s.Y)-(first.X-s.X)*(last.Y-first.Y)) / length
+			maxDeviation = math.Max(maxDeviation, deviation)
+		}
+		sum += maxDeviation / length
+	}
+
+	if len(reaches) == 0 {
+		return MetricResult{Value: 0.0, Calculated: false, SampleSize: 0}
+	}
+	return MetricResult{Value: sum / float64(len(reaches)), Calculated: true, SampleSize: len(reaches)}
+}
+
+// calculateSubmovementCount returns the average number of submovements per reach,
+// counted as separate velocity peaks. A new submovement starts whenever speed
+// rises again after dropping to a local minimum by more than 10% of the reach's
+// peak speed, so sampling jitter isn't counted.
+func calculateSubmovementCount(questionID *string, interactions *models.InteractionData) MetricResult {
+	reaches := extractReaches(questionID, interactions, 4)
+
+	sum := 0.0
+	for _, r := range reaches {
+		_, speeds := r.speeds()
+		peak := 0.0
+		for _, v := range speeds {
+			peak = math.Max(peak, v)
+		}
+		threshold := peak * 0.1
+
+		submovements := 0
+		rising := false
+		trough := 0.0 // Movements start from rest
+		crest := 0.0
+		for _, v := range speeds {
+			if rising {
+				if v > crest {
+					crest = v
+				} else if crest-v > threshold {
+					rising = false
+					trough = v
+				}
+			} else {
+				if v < trough {
+					trough = v
+				} else if v-trough > threshold {
+					rising = true
+					crest = v
+					submovements++
+				}
+			}
+		}
+		sum += float64(max(submovements, 1))
+	}
+
+	if len(reaches) == 0 {
+		return MetricResult{Value: 0.0, Calculated: false, SampleSize: 0}
+	}
+	return MetricResult{Value: sum / float64(len(reaches)), Calculated: true, SampleSize: len(reaches)}
+}
+
+// calculateFittsThroughput returns the average Fitts' law throughput in bits per
+// second: the index of difficulty log2(D/W + 1) divided by movement time, where D
+// is the distance to the target centre and W the target's smaller dimension. Only
+// clicks with a recorded target size are used.
+func calculateFittsThroughput(questionID *string, interactions *models.InteractionData) MetricResult {
+	reaches := extractReaches(questionID, interactions, 2)
+
+	sum := 0.0
+	count := 0
+	for _, r := range reaches {
+		width := math.Min(r.click.TargetWidth, r.click.TargetHeight)
+		if width <= 0 {
+			continue
+		}
+		first := r.samples[0]
+		distance := math.Hypot(r.click.TargetX-first.X, r.click.TargetY-first.Y)
+		indexOfDifficulty := math.Log2(distance/width + 1)
+		sum += indexOfDifficulty / (r.duration() / 1000)
+		count++
+	}
+
+	if count == 0 {
+		return MetricResult{Value: 0.0, Calculated: false, SampleSize: 0}
+	}
+	return MetricResult{Value: sum / float64(count), Calculated: true, SampleSize: count}
+}
@@ -15,8 +15,12 @@ type CalculatedMetrics struct {
 	QuestionMetrics []models.AssessmentMetric
 }
 
-// CalculateInteractionMetrics calculates all interaction metrics
-func CalculateInteractionMetrics(interactions *models.InteractionData) *CalculatedMetrics {
+// CalculateInteractionMetrics calculates all interaction metrics. Mouse and pen
+// input is scored with the mouse metrics and touch input with the touch metrics,
+// so a session recorded on a phone is not judged by cursor-based measures.
+// Decision metrics are only calculated for the questions in choiceQuestions,
+// the ones answered by picking an option.
+func CalculateInteractionMetrics(interactions *models.InteractionData, choiceQuestions map[string]bool) *CalculatedMetrics {
 	result := &CalculatedMetrics{
 		GlobalMetrics:   []models.AssessmentMetric{},
 		QuestionMetrics: []models.AssessmentMetric{},
@@ -24,61 +28,46 @@ func CalculateInteractionMetrics(interactions *models.InteractionData) *Calculat
 
 	globalInteractions := &models.InteractionData{}
 	questionInteractions := make(map[string]*models.InteractionData)
-
-	for _, m := range interactions.MouseMovements {
-		if m.QuestionID == "" {
-			globalInteractions.MouseMovements = append(globalInteractions.MouseMovements, m)
-		} else {
-			if _, ok := questionInteractions[m.QuestionID]; !ok {
-				questionInteractions[m.QuestionID] = &models.InteractionData{}
-			}
-			questionInteractions[m.QuestionID].MouseMovements = append(questionInteractions[m.QuestionID].MouseMovements, m)
+	bucket := func(questionID string) *models.InteractionData {
+		if questionID == "" {
+			return globalInteractions
+		}
+		if _, ok := questionInteractions[questionID]; !ok {
+			questionInteractions[questionID] = &models.InteractionData{}
 		}
+		return questionInteractions[questionID]
 	}
 
+	for _, m := range interactions.MouseMovements {
+		b := bucket(m.QuestionID)
+		b.MouseMovements = append(b.MouseMovements, m)
+	}
 	for _, i := range interactions.MouseInteractions {
-		if i.QuestionID == "" {
-			globalInteractions.MouseInteractions = append(globalInteractions.MouseInteractions, i)
-		} else {
-			if _, ok := questionInteractions[i.QuestionID]; !ok {
-				questionInteractions[i.QuestionID] = &models.InteractionData{}
-			}
-			questionInteractions[i.QuestionID].MouseInteractions = append(questionInteractions[i.QuestionID].MouseInteractions, i)
-		}
+		b := bucket(i.QuestionID)
+		b.MouseInteractions = append(b.MouseInteractions, i)
 	}
-
 	for _, k := range interactions.KeyboardEvents {
-		if k.QuestionID == "" {
-			globalInteractions.KeyboardEvents = append(globalInteractions.KeyboardEvents, k)
-		} else {
-			if _, ok := questionInteractions[k.QuestionID]; !ok {
-				questionInteractions[k.QuestionID] = &models.InteractionData{}
-			}
-			questionInteractions[k.QuestionID].KeyboardEvents = append(questionInteractions[k.QuestionID].KeyboardEvents, k)
-		}
+		b := bucket(k.QuestionID)
+		b.KeyboardEvents = append(b.KeyboardEvents, k)
 	}
-
-	// --- Step 1: Calculate metrics ONLY for the global data ---
-	globalMouseMetrics := map[string]MetricResult{
-		"click_precision":      calculateClickPrecision(nil, globalInteractions),
-		"path_efficiency":      calculatePathEfficiency(nil, globalInteractions),
-		"overshoot_rate":       calculateOvershootRate(nil, globalInteractions),
-		"average_velocity":     calculateAverageVelocity(nil, globalInteractions),
-		"velocity_variability": calculateVelocityVariability(nil, globalInteractions),
+	for _, g := range interactions.TouchGestures {
+		b := bucket(g.QuestionID)
+		b.TouchGestures = append(b.TouchGestures, g)
 	}
-	globalKeyboardMetrics := calculateKeyboardMetrics(nil, globalInteractions)
-
-	for key, val := range globalKeyboardMetrics {
-		globalMouseMetrics[key] = val
+	for _, e := range interactions.PageEvents {
+		b := bucket(e.QuestionID)
+		b.PageEvents = append(b.PageEvents, e)
 	}
 
-	for metricKey, metricResult := range globalMouseMetrics {
+	// --- Step 1: Calculate metrics ONLY for the global data ---
+	for metricKey, metricResult := range calculateMetricFamilies(nil, globalInteractions, false) {
 		if metricResult.Calculated {
 			result.GlobalMetrics = append(result.GlobalMetrics, models.AssessmentMetric{
-				QuestionID:  "global",
-				MetricKey:   metricKey,
-				MetricValue: metricResult.Value,
-				SampleSize:  metricResult.SampleSize,
+				QuestionID:       "global",
+				MetricKey:        metricKey,
+				MetricValue:      metricResult.Value,
+				SampleSize:       metricResult.SampleSize,
+				AlgorithmVersion: InteractionMetricVersion(metricKey),
 			})
 		}
 	}
@@ -87,26 +76,14 @@ func CalculateInteractionMetrics(interactions *models.InteractionData) *Calculat
 	for questionID, specificInteractions := range questionInteractions {
 		qID := questionID // Create a copy for the pointer
 
-		qMouseMetrics := map[string]MetricResult{
-			"click_precision":      calculateClickPrecision(&qID, specificInteractions),
-			"path_efficiency":      calculatePathEfficiency(&qID, specificInteractions),
-			"overshoot_rate":       calculateOvershootRate(&qID, specificInteractions),
-			"average_velocity":     calculateAverageVelocity(&qID, specificInteractions),
-			"velocity_variability": calculateVelocityVariability(&qID, specificInteractions),
-		}
-		qKeyboardMetrics := calculateKeyboardMetrics(&qID, specificInteractions)
-
-		for key, val := range qKeyboardMetrics {
-			qMouseMetrics[key] = val
-		}
-
-		for metricKey, metricResult := range qMouseMetrics {
+		for metricKey, metricResult := range calculateMetricFamilies(&qID, specificInteractions, choiceQuestions[questionID]) {
 			if metricResult.Calculated {
 				result.QuestionMetrics = append(result.QuestionMetrics, models.AssessmentMetric{
-					QuestionID:  questionID,
-					MetricKey:   metricKey,
-					MetricValue: metricResult.Value,
-					SampleSize:  metricResult.SampleSize,
+					QuestionID:       questionID,
+					MetricKey:        metricKey,
+					MetricValue:      metricResult.Value,
+					SampleSize:       metricResult.SampleSize,
+					AlgorithmVersion: InteractionMetricVersion(metricKey),
 				})
 			}
 		}
@@ -114,3 +91,38 @@ func CalculateInteractionMetrics(interactions *models.InteractionData) *Calculat
 
 	return result
 }
+
+// calculateMetricFamilies scores one bucket of interaction data with the mouse,
+// touch, keyboard and interruption metric families, and with the decision
+// metrics when the bucket belongs to a choice question.
+func calculateMetricFamilies(questionID *string, interactions *models.InteractionData, choice bool) map[string]MetricResult {
+	pointer, touch := splitByPointer(interactions)
+
+	results := map[string]MetricResult{
+		"click_precision":      calculateClickPrecision(questionID, pointer),
+		"path_efficiency":      calculatePathEfficiency(questionID, pointer),
+		"overshoot_rate":       calculateOvershootRate(questionID, pointer),
+		"average_velocity":     calculateAverageVelocity(questionID, pointer),
+		"velocity_variability": calculateVelocityVariability(questionID, pointer),
+		"peak_velocity_time":   calculatePeakVelocityTime(questionID, pointer),
+		"normalized_jerk":      calculateNormalizedJerk(questionID, pointer),
+		"curvature_index":      calculateCurvatureIndex(questionID, pointer),
+		"submovement_count":    calculateSubmovementCount(questionID, pointer),
+		"fitts_throughput":     calculateFittsThroughput(questionID, pointer),
+	}
+	for key, val := range calculateTouchMetrics(questionID, touch) {
+		results[key] = val
+	}
+	for key, val := range calculateKeyboardMetrics(questionID, interactions) {
+		results[key] = val
+	}
+	if choice {
+		for key, val := range calculateDecisionMetrics(questionID, interactions) {
+			results[key] = val
+		}
+	}
+	for key, val := range calculateInterruptionMetrics(questionID, interactions) {
+		results[key] = val
+	}
+	return results
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"math"
+
+	"crapp-go/internal/config"
+	"crapp-go/internal/models"
+)
+
+// DataQuality counts the events dropped from a chunk of interaction data.
+// ClockJumps are events whose timestamp went backwards; they are included in
+// DroppedEvents along with every other implausible event.
+type DataQuality struct {
+	DroppedEvents int
+	ClockJumps    int
+}
+
+// EventCount returns the number of events of every kind in data.
+func EventCount(data *models.InteractionData) int {
+	return len(data.MouseMovements) + len(data.MouseInteractions) + len(data.KeyboardEvents) +
+		len(data.TouchGestures) + len(data.PageEvents)
+}
+
+// SanitizeInteractionData drops implausible events from data in place: timestamps
+// that go backwards or fall outside the tracker session, coordinates beyond the
+// configured limit (including viewport sizes) and movement samples implying
+// impossible velocities. Each event stream must arrive in the order the client recorded it.
+func SanitizeInteractionData(data *models.InteractionData, limits config.InteractionLimits) DataQuality {
+	var q DataQuality
+
+	// timeline drops events whose timestamp is out of session or earlier than the
+	// previous kept event of the same stream.
+	timeline := func() func(t float64) bool {
+		last := math.Inf(-1)
+		return func(t float64) bool {
+			if t < 0 || t > limits.MaxSessionDuration {
+				q.DroppedEvents++
+				return false
+			}
+			if t < last {
+				q.ClockJumps++
+				q.DroppedEvents++
+				return false
+			}
+			last = t
+			return true
+		}
+	}
+	onScreen := func(coords ...float64) bool {
+		for _, c := range coords {
+			if math.Abs(c) > limits.MaxCoordinate {
+				return false
+			}
+		}
+		return true
+	}
+
+	keepTime := timeline()
+	movements := data.MouseMovements[:0]
+	for _, m := range data.MouseMovements {
+		if !keepTime(m.Timestamp) {
+			continue
+		}
+		if !onScreen(m.X, m.Y) {
+			q.DroppedEvents++
+			continue
+		}
+		if n := len(movements); n > 0 {
+			prev := movements[n-1]
+			dt := (m.Timestamp - prev.Timestamp) / 1000
+			dist := math.Hypot(m.X-prev.X, m.Y-prev.Y)
+			// Samples with the same timestamp can only be at the same place
+			if (dt == 0 && dist > 0) || (dt > 0 && dist/dt > limits.MaxVelocity) {
+				q.DroppedEvents++
+				continue
+			}
+		}
+		movements = append(movements, m)
+	}
+	data.MouseMovements = movements
+
+	keepTime = timeline()
+	clicks := data.MouseInteractions[:0]
+	for _, i := range data.MouseInteractions {
+		if !keepTime(i.Timestamp) {
+			continue
+		}
+		if !onScreen(i.ClickX, i.ClickY, i.TargetX, i.TargetY, i.TargetWidth, i.TargetHeight) || i.Duration < 0 {
+			q.DroppedEvents++
+			continue
+		}
+		clicks = append(clicks, i)
+	}
+	data.MouseInteractions = clicks
+
+	keepTime = timeline()
+	keys := data.KeyboardEvents[:0]
+	for _, k := range data.KeyboardEvents {
+		if keepTime(k.Timestamp) {
+			keys = append(keys, k)
+		}
+	}
+	data.KeyboardEvents = keys
+
+	keepTime = timeline()
+	gestures := data.TouchGestures[:0]
+	for _, g := range data.TouchGestures {
+		// Gestures are recorded when they end, so they are in order of end time.
+		if !keepTime(g.Timestamp + g.Duration) {
+			continue
+		}
+		if !onScreen(g.StartX, g.StartY, g.EndX, g.EndY) || g.Duration < 0 || g.Timestamp < 0 {
+			q.DroppedEvents++
+			continue
+		}
+		if g.Duration > 0 && math.Hypot(g.EndX-g.StartX, g.EndY-g.StartY)/(g.Duration/1000) > limits.MaxVelocity {
+			q.DroppedEvents++
+			continue
+		}
+		gestures = append(gestures, g)
+	}
+	data.TouchGestures = gestures
+
+	keepTime = timeline()
+	pageEvents := data.PageEvents[:0]
+	for _, e := range data.PageEvents {
+		if !keepTime(e.Timestamp) {
+			continue
+		}
+		if !onScreen(e.Width, e.Height) || e.Width < 0 || e.Height < 0 {
+			q.DroppedEvents++
+			continue
+		}
+		pageEvents = append(pageEvents, e)
+	}
+	data.PageEvents = pageEvents
+
+	return q
+}
@@ -21,8 +21,10 @@ type TrailMakingData struct {
 	PartBErrors         int            `json:"partBErrors"`
 	PartACompletionTime float64        `json:"partACompletionTime"`
 	PartBCompletionTime float64        `json:"partBCompletionTime"`
+	TimedOut            bool           `json:"timedOut"` // A part hit its time limit before it was finished
 	Clicks              []Click        `json:"clicks"`
 	Settings            map[string]any `json:"settings"`
+	FocusData
 }
 
 // Click represents a single interaction during the Trail Making Test
@@ -32,10 +34,21 @@ type Click struct {
 	Time        float64 `json:"time"`
 	TargetItem  int     `json:"targetItem"`
 	CurrentPart string  `json:"currentPart"`
+	IsPractice  bool    `json:"isPractice"`
 }
 
-// Calculate metrics for Trail Making Test
+// Part names as reported in Click.CurrentPart.
+const (
+	trailPartA = "Part A"
+	trailPartB = "Part B"
+)
+
+// Calculate metrics for Trail Making Test. Times and errors are scored from the
+// clicks of each part rather than taken from the client's totals.
 func CalculateTrailMetrics(data *TrailMakingData) *models.TMTResult {
+	partA := scoreTrailPart(data, trailPartA, data.PartAStartTime, data.PartAEndTime, data.PartACompletionTime)
+	partB := scoreTrailPart(data, trailPartB, data.PartBStartTime, data.PartBEndTime, data.PartBCompletionTime)
+
 	// Create Trail Making Test result model
 	return &models.TMTResult{
 		// Time fields
@@ -43,15 +56,15 @@ func CalculateTrailMetrics(data *TrailMakingData) *models.TMTResult {
 		//TestEndTime:   time.UnixMilli(int64(data.TestEndTime)),
 
 		// Part A metrics
-		PartACompletionTime: data.PartACompletionTime,
-		PartAErrors:         data.PartAErrors,
+		PartACompletionTime: partA.completionTime,
+		PartAErrors:         partA.errors,
 
 		// Part B metrics
-		PartBCompletionTime: data.PartBCompletionTime,
-		PartBErrors:         data.PartBErrors,
+		PartBCompletionTime: partB.completionTime,
+		PartBErrors:         partB.errors,
 
 		// Calculated metrics
-		BToARatio: calculateBToARatio(data),
+		BToARatio: calculateBToARatio(partA.completionTime, partB.completionTime),
 
 		// Store the raw data for future analysis
 		RawData:   serializeTrailData(data),
@@ -59,12 +72,54 @@ func CalculateTrailMetrics(data *TrailMakingData) *models.TMTResult {
 	}
 }
 
+// trailPart is the score of one part of the test.
+type trailPart struct {
+	completionTime float64 // Milliseconds from the start of the part to its end
+	errors         int
+}
+
+// scoreTrailPart replays the clicks of one part, skipping practice clicks. Items
+// are numbered in the order they must be connected, so a click on the next item
+// connects it and a click on an item further along is an error. Clicks on empty
+// space or on items already connected are ignored, as on the client.
+//
+// The part runs from start to the click that made its last connection, or to end
+// if the test timed out during it. Payloads recorded before the client sent part
+// start and end times fall back to the completion time it reported.
+func scoreTrailPart(data *TrailMakingData, part string, start, end, reported float64) trailPart {
+	var score trailPart
+	next := 0
+	lastConnection := start
+	for _, click := range data.Clicks {
+		if click.IsPractice || click.CurrentPart != part {
+			continue
+		}
+		switch {
+		case click.TargetItem == next:
+			next++
+			lastConnection = click.Time
+		case click.TargetItem > next:
+			score.errors++
+		}
+	}
+
+	switch {
+	case end <= 0:
+		score.completionTime = reported
+	case data.TimedOut && end > lastConnection:
+		score.completionTime = end - start
+	default:
+		score.completionTime = lastConnection - start
+	}
+	return score
+}
+
 // Calculate B/A ratio (important clinical measure)
-func calculateBToARatio(data *TrailMakingData) float64 {
-	if data.PartACompletionTime <= 0 {
+func calculateBToARatio(partA, partB float64) float64 {
+	if partA <= 0 {
 		return 0
 	}
-	return data.PartBCompletionTime / data.PartACompletionTime
+	return partB / partA
 }
 
 // Serialize trail data to JSON
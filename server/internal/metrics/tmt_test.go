@@ -0,0 +1,91 @@
+package metrics
+
+import "testing"
+
+func TestCalculateTrailMetrics(t *testing.T) {
+	practice := []Click{
+		{TargetItem: 0, Time: 100, CurrentPart: "Practice", IsPractice: true},
+		{TargetItem: 2, Time: 300, CurrentPart: "Practice", IsPractice: true},
+		{TargetItem: 1, Time: 900, CurrentPart: "Practice", IsPractice: true},
+	}
+	partA := []Click{
+		{TargetItem: 0, Time: 1500, CurrentPart: "Part A"},
+		{TargetItem: 2, Time: 1800, CurrentPart: "Part A"},  // Ahead of the next item
+		{TargetItem: -1, Time: 1900, CurrentPart: "Part A"}, // Empty space
+		{TargetItem: 1, Time: 2100, CurrentPart: "Part A"},
+		{TargetItem: 0, Time: 2200, CurrentPart: "Part A"}, // Already connected
+		{TargetItem: 2, Time: 2600, CurrentPart: "Part A"},
+	}
+	partB := []Click{
+		{TargetItem: 0, Time: 4000, CurrentPart: "Part B"},
+		{TargetItem: 1, Time: 4500, CurrentPart: "Part B"},
+		{TargetItem: 2, Time: 5200, CurrentPart: "Part B"},
+	}
+	var clicks []Click
+	clicks = append(clicks, practice...)
+	clicks = append(clicks, partA...)
+	clicks = append(clicks, partB...)
+
+	tests := []struct {
+		name      string
+		data      TrailMakingData
+		wantATime float64
+		wantAErrs int
+		wantBTime float64
+		wantBErrs int
+		wantBToA  float64
+	}{
+		{
+			// The client's totals include the practice round and are ignored.
+			name: "practice clicks are not scored",
+			data: TrailMakingData{
+				Clicks:         clicks,
+				PartAStartTime: 1000, PartAEndTime: 2600, PartACompletionTime: 2600, PartAErrors: 2,
+				PartBStartTime: 3200, PartBEndTime: 5200, PartBCompletionTime: 2000,
+			},
+			wantATime: 1600, wantAErrs: 1, wantBTime: 2000, wantBErrs: 0, wantBToA: 1.25,
+		},
+		{
+			name: "practice clicks labelled with a scored part",
+			data: TrailMakingData{
+				Clicks: append([]Click{
+					{TargetItem: 1, Time: 200, CurrentPart: "Part A", IsPractice: true},
+					{TargetItem: 0, Time: 250, CurrentPart: "Part A", IsPractice: true},
+				}, partA...),
+				PartAStartTime: 1000, PartAEndTime: 2600,
+			},
+			wantATime: 1600, wantAErrs: 1,
+		},
+		{
+			name: "timed out during part B",
+			data: TrailMakingData{
+				Clicks:         append(append([]Click{}, partA...), partB[:2]...),
+				PartAStartTime: 1000, PartAEndTime: 2600,
+				PartBStartTime: 3200, PartBEndTime: 33200,
+				TimedOut: true,
+			},
+			wantATime: 1600, wantAErrs: 1, wantBTime: 30000, wantBToA: 18.75,
+		},
+		{
+			// Payloads from before part times were sent keep the reported times.
+			name: "without part times",
+			data: TrailMakingData{
+				Clicks:              clicks,
+				PartACompletionTime: 1700, PartAErrors: 5,
+				PartBCompletionTime: 3400, PartBErrors: 5,
+			},
+			wantATime: 1700, wantAErrs: 1, wantBTime: 3400, wantBErrs: 0, wantBToA: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateTrailMetrics(&tt.data)
+			if got.PartACompletionTime != tt.wantATime || got.PartAErrors != tt.wantAErrs ||
+				got.PartBCompletionTime != tt.wantBTime || got.PartBErrors != tt.wantBErrs || got.BToARatio != tt.wantBToA {
+				t.Errorf("part A %v ms, %d errors; part B %v ms, %d errors; B/A %v; want %v, %d; %v, %d; %v",
+					got.PartACompletionTime, got.PartAErrors, got.PartBCompletionTime, got.PartBErrors, got.BToARatio,
+					tt.wantATime, tt.wantAErrs, tt.wantBTime, tt.wantBErrs, tt.wantBToA)
+			}
+		})
+	}
+}
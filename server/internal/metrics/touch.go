@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"math"
+
+	"crapp-go/internal/models"
+)
+
+// calculateTouchMetrics calculates the touch metric family: tap accuracy, tap
+// duration, swipe velocity and the multi-touch error rate.
+func calculateTouchMetrics(questionID *string, interactions *models.InteractionData) map[string]MetricResult {
+	return map[string]MetricResult{
+		"tap_accuracy":           calculateTapAccuracy(questionID, interactions),
+		"tap_duration":           calculateTapDuration(questionID, interactions),
+		"swipe_velocity":         calculateSwipeVelocity(questionID, interactions),
+		"multi_touch_error_rate": calculateMultiTouchErrorRate(questionID, interactions),
+	}
+}
+
+// calculateTapAccuracy scores how close taps land to the centre of their target,
+// from 1 (dead centre) to 0 (on or beyond the target's edge). Unlike click
+// precision it uses the target's real size, since fingers are much less precise
+// than a cursor and small targets would otherwise dominate.
+func calculateTapAccuracy(questionID *string, interactions *models.InteractionData) MetricResult {
+	taps := filterInteractionsByQuestion(questionID, interactions)
+
+	sum := 0.0
+	count := 0
+	for _, tap := range taps {
+		if tap.TargetWidth <= 0 || tap.TargetHeight <= 0 {
+			continue // Target size wasn't recorded
+		}
+		dx := (tap.ClickX - tap.TargetX) / (tap.TargetWidth / 2)
+		dy := (tap.ClickY - tap.TargetY) / (tap.TargetHeight / 2)
+		// Distance from the centre as a fraction of the way to the target's edge
+		normalized := math.Min(math.Sqrt(dx*dx+dy*dy), 1)
+		sum += 1 - normalized
+		count++
+	}
+
+	if count == 0 {
+		return MetricResult{Value: 0.0, Calculated: false, SampleSize: 0}
+	}
+	return MetricResult{Value: sum / float64(count), Calculated: true, SampleSize: count}
+}
+
+// calculateTapDuration returns the average contact time of single-finger taps in milliseconds.
+func calculateTapDuration(questionID *string, interactions *models.InteractionData) MetricResult {
+	gestures := filterGesturesByQuestion(questionID, interactions)
+
+	sum := 0.0
+	count := 0
+	for _, g := range gestures {
+		if g.Type == models.GestureTap && g.PointerCount <= 1 && g.Duration > 0 {
+			sum += g.Duration
+			count++
+		}
+	}
+
+	if count == 0 {
+		return MetricResult{Value: 0.0, Calculated: false, SampleSize: 0}
+	}
+	return MetricResult{Value: sum / float64(count), Calculated: true, SampleSize: count}
+}
+
+// calculateSwipeVelocity returns the average speed of single-finger swipes in
+// pixels per second, measured from first contact to release.
+func calculateSwipeVelocity(questionID *string, interactions *models.InteractionData) MetricResult {
+	gestures := filterGesturesByQuestion(questionID, interactions)
+
+	sum := 0.0
+	count := 0
+	for _, g := range gestures {
+		if g.Type != models.GestureSwipe || g.PointerCount > 1 || g.Duration <= 0 {
+			continue
+		}
+		dx := g.EndX - g.StartX
+		dy := g.EndY - g.StartY
+		sum += math.Sqrt(dx*dx+dy*dy) / (g.Duration / 1000)
+		count++
+	}
+
+	if count == 0 {
+		return MetricResult{Value: 0.0, Calculated: false, SampleSize: 0}
+	}
+	return MetricResult{Value: sum / float64(count), Calculated: true, SampleSize: count}
+}
+
+// calculateMultiTouchErrorRate returns the fraction of gestures made with more than
+// one finger. The assessment never asks for multi-finger input, so these are
+// accidental touches (e.g. a palm or second finger resting on the screen).
+func calculateMultiTouchErrorRate(questionID *string, interactions *models.InteractionData) MetricResult {
+	gestures := filterGesturesByQuestion(questionID, interactions)
+	if len(gestures) == 0 {
+		return MetricResult{Value: 0.0, Calculated: false, SampleSize: 0}
+	}
+
+	errors := 0
+	for _, g := range gestures {
+		if g.PointerCount > 1 {
+			errors++
+		}
+	}
+	return MetricResult{
+		Value:      float64(errors) / float64(len(gestures)),
+		Calculated: true,
+		SampleSize: len(gestures),
+	}
+}
+
+func filterGesturesByQuestion(questionID *string, interactions *models.InteractionData) []models.TouchGesture {
+	if questionID == nil {
+		return interactions.TouchGestures
+	}
+
+	filtered := make([]models.TouchGesture, 0)
+	for _, gesture := range interactions.TouchGestures {
+		if gesture.QuestionID == *questionID {
+			filtered = append(filtered, gesture)
+		}
+	}
+
+	return filtered
+}
+
+// splitByPointer separates touch input from mouse and pen input so each can be
+// scored with the metric family that suits it. Keyboard events go with neither.
+func splitByPointer(interactions *models.InteractionData) (pointer, touch *models.InteractionData) {
+	pointer = &models.InteractionData{}
+	touch = &models.InteractionData{TouchGestures: interactions.TouchGestures}
+
+	for _, m := range interactions.MouseMovements {
+		if m.IsTouch() {
+			touch.MouseMovements = append(touch.MouseMovements, m)
+		} else {
+			pointer.MouseMovements = append(pointer.MouseMovements, m)
+		}
+	}
+	for _, i := range interactions.MouseInteractions {
+		if i.IsTouch() {
+			touch.MouseInteractions = append(touch.MouseInteractions, i)
+		} else {
+			pointer.MouseInteractions = append(pointer.MouseInteractions, i)
+		}
+	}
+	return pointer, touch
+}
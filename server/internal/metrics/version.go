@@ -0,0 +1,30 @@
+package metrics
+
+// Algorithm versions are stamped on every stored metric so that values computed
+// by different revisions of an algorithm can be told apart. Bump the matching
+// version whenever a change would alter previously computed values.
+const (
+	CPTAlgorithmVersion = 1
+	DSTAlgorithmVersion = 1
+	TMTAlgorithmVersion = 2 // 2: scored from the clicks of each part, without practice clicks
+)
+
+// interactionMetricVersions holds the algorithm version of each mouse and keyboard
+// metric. Metrics that are not listed are at version 1.
+var interactionMetricVersions = map[string]int{
+	// 2: computed from key categories; key holds are matched by press ID
+	"typing_speed":                  2,
+	"average_key_hold_time":         2,
+	"key_press_variability":         2,
+	"correction_rate":               2,
+	"immediate_correction_tendency": 2,
+	"keyboard_fluency":              2,
+}
+
+// InteractionMetricVersion returns the current algorithm version of an interaction metric.
+func InteractionMetricVersion(metricKey string) int {
+	if version, ok := interactionMetricVersions[metricKey]; ok {
+		return version
+	}
+	return 1
+}
@@ -24,6 +24,12 @@ type Question struct {
 	MaxLength   int      `yaml:"max_length,omitempty"`
 }
 
+// IsChoice reports whether the question is answered by picking one of its
+// options, as radio and drop-down questions are.
+func (q Question) IsChoice() bool {
+	return q.Type == "radio" || q.Type == "drop_down"
+}
+
 // Option struct for question choices
 type Option struct {
 	Value       string `yaml:"value"`
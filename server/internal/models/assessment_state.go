@@ -16,6 +16,17 @@ type AssessmentState struct {
 	CurrentQuestionIndex int
 	CreatedAt            time.Time
 	UpdatedAt            time.Time
+
+	// An assessment marked invalid, e.g. because it was rushed or interrupted, is
+	// kept but left out of charts and statistics.
+	InvalidatedAt      *time.Time
+	InvalidatedByID    *int
+	InvalidationReason string
+}
+
+// IsInvalidated reports whether the assessment has been marked invalid.
+func (s *AssessmentState) IsInvalidated() bool {
+	return s.InvalidatedAt != nil
 }
 
 // Need to define a new model for the answers table
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Baseline window methods.
+const (
+	BaselineFirstN    = "first_n"    // The user's first N valid assessments
+	BaselineDateRange = "date_range" // Valid assessments taken within a chosen date range
+)
+
+// Baseline is a user's reference level for one metric, computed from the valid
+// assessments inside a window they choose. It is stored so the same reference is
+// used every time the metric is charted, and recomputed when the window changes.
+type Baseline struct {
+	gorm.Model
+	UserID     int    `gorm:"uniqueIndex:idx_baseline_metric"`
+	User       User   `gorm:"foreignKey:UserID"`
+	QuestionID string `gorm:"uniqueIndex:idx_baseline_metric"`
+	MetricKey  string `gorm:"uniqueIndex:idx_baseline_metric"`
+
+	// The window the baseline covers
+	Method           string
+	FirstN           int        // Used by BaselineFirstN
+	StartDate        *time.Time // Used by BaselineDateRange, inclusive
+	EndDate          *time.Time // Used by BaselineDateRange, inclusive
+	AlgorithmVersion int        // Algorithm version the values were filtered to; 0 for the latest recomputation
+
+	// Statistics of the values inside the window
+	Mean float64
+	SD   float64 // Sample standard deviation
+	N    int
+}
+
+// SameWindow reports whether b and other cover the same assessments.
+func (b *Baseline) SameWindow(other *Baseline) bool {
+	if b.Method != other.Method || b.AlgorithmVersion != other.AlgorithmVersion {
+		return false
+	}
+	if b.Method == BaselineFirstN {
+		return b.FirstN == other.FirstN
+	}
+	return sameDate(b.StartDate, other.StartDate) && sameDate(b.EndDate, other.EndDate)
+}
+
+// IsFilled reports whether every assessment the window can hold has been taken.
+// Until then, new assessments still change the baseline.
+func (b *Baseline) IsFilled() bool {
+	if b.Method == BaselineFirstN {
+		return b.N >= b.FirstN
+	}
+	return b.EndDate != nil && b.UpdatedAt.After(b.EndDate.AddDate(0, 0, 1))
+}
+
+// Covers reports whether a value falls inside the baseline window, given its
+// position among the user's valid values (oldest first) and when it was taken.
+func (b *Baseline) Covers(index int, takenAt time.Time) bool {
+	if b.Method == BaselineFirstN {
+		return index < b.FirstN
+	}
+	return b.StartDate != nil && b.EndDate != nil &&
+		!takenAt.Before(*b.StartDate) && takenAt.Before(b.EndDate.AddDate(0, 0, 1))
+}
+
+// HasSpread reports whether the baseline has enough values with enough spread for
+// z-scores.
+func (b *Baseline) HasSpread() bool {
+	return b.N >= 2 && b.SD > 0
+}
+
+// ZScore returns how many baseline standard deviations value is from the mean.
+func (b *Baseline) ZScore(value float64) float64 {
+	return (value - b.Mean) / b.SD
+}
+
+// PercentChange returns the change of value from the baseline mean, in percent.
+func (b *Baseline) PercentChange(value float64) float64 {
+	return (value - b.Mean) / math.Abs(b.Mean) * 100
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
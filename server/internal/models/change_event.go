@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Change directions.
+const (
+	ChangeImprovement = "improvement"
+	ChangeDecline     = "decline"
+)
+
+// ChangeEvent records a cognitive test score that differs from the user's baseline
+// by more than measurement noise, as judged by its reliable change index. Events
+// are recomputed whenever the baseline or the scores behind them change.
+type ChangeEvent struct {
+	gorm.Model
+	UserID       int  `gorm:"index"`
+	User         User `gorm:"foreignKey:UserID"`
+	AssessmentID uint
+	Assessment   AssessmentState `gorm:"foreignKey:AssessmentID"`
+	QuestionID   string
+	MetricKey    string
+	Date         time.Time // When the assessment was taken
+	Value        float64
+	BaselineMean float64
+	RCI          float64 // Reliable change index; positive when the score rose
+	Direction    string  // ChangeImprovement or ChangeDecline
+}
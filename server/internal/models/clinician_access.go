@@ -0,0 +1,14 @@
+package models
+
+import "gorm.io/gorm"
+
+// ClinicianAccess lets a clinician review a patient's assessment history and mark
+// assessments invalid. Patients grant it to another user from their profile and
+// may revoke it at any time.
+type ClinicianAccess struct {
+	gorm.Model
+	PatientID   uint `gorm:"uniqueIndex:idx_clinician_access"`
+	Patient     User `gorm:"foreignKey:PatientID"`
+	ClinicianID uint `gorm:"uniqueIndex:idx_clinician_access;index"`
+	Clinician   User `gorm:"foreignKey:ClinicianID"`
+}
@@ -19,6 +19,8 @@ type CPTResult struct {
 	DetectionRate       float64
 	OmissionErrorRate   float64
 	CommissionErrorRate float64
+	Validity            `gorm:"embedded"`
+	Provenance          `gorm:"embedded"`
 	RawData             json.RawMessage `gorm:"type:jsonb"`
 }
 
@@ -33,4 +35,5 @@ type CPTEvent struct {
 	PresentedAt   *float64
 	ResponseTime  *float64
 	StimulusIndex *int
+	IsPractice    bool `gorm:"default:false"` // Practice trials are stored but never scored
 }
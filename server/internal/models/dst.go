@@ -15,6 +15,8 @@ type DSTResult struct {
 	HighestSpanAchieved int
 	TotalTrials         int
 	CorrectTrials       int
+	Validity            `gorm:"embedded"`
+	Provenance          `gorm:"embedded"`
 	RawData             json.RawMessage `gorm:"type:jsonb"`
 	CreatedAt           time.Time
 }
@@ -22,12 +24,13 @@ type DSTResult struct {
 // DSTAttempt represents a single trial within a Digit Span Test.
 type DSTAttempt struct {
 	gorm.Model
-	ResultID  uint
-	Result    DSTResult `gorm:"foreignKey:ResultID"`
-	Span      int
-	Trial     int
-	Sequence  string
-	Input     string
-	IsCorrect bool
-	Timestamp float64
+	ResultID   uint
+	Result     DSTResult `gorm:"foreignKey:ResultID"`
+	Span       int
+	Trial      int
+	Sequence   string
+	Input      string
+	IsCorrect  bool
+	Timestamp  float64
+	IsPractice bool `gorm:"default:false"` // Practice trials are stored but never scored
 }
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InteractionChunk is one incremental upload of interaction events for a question.
+// Chunks are keyed by the client's tracker session and sequence number, so a
+// retransmitted chunk is only ever stored once.
+type InteractionChunk struct {
+	gorm.Model
+	AssessmentID uint            `gorm:"uniqueIndex:idx_interaction_chunk_seq"`
+	Assessment   AssessmentState `gorm:"foreignKey:AssessmentID"`
+	SessionID    string          `gorm:"size:64;uniqueIndex:idx_interaction_chunk_seq"`
+	Sequence     int             `gorm:"uniqueIndex:idx_interaction_chunk_seq"`
+	SessionStart float64         // Epoch milliseconds at which the tracker session started
+	QuestionID   string
+	// Implausible events dropped before storing, kept after Data is cleared
+	DroppedEvents int
+	ClockJumps    int
+	// InteractionData with timestamps relative to SessionStart. Cleared once the
+	// chunk has been folded into the assessment's event streams.
+	Data json.RawMessage `gorm:"type:jsonb"`
+}
+
+// QuarantinedInteractionChunk holds interaction data that could not be attributed
+// to an assessment of the sending user, or that names questions outside the
+// assessment's question order. It is kept for inspection but never scored.
+type QuarantinedInteractionChunk struct {
+	gorm.Model
+	UserID       int    `gorm:"uniqueIndex:idx_quarantined_chunk_seq"`
+	AssessmentID uint   // As claimed by the client
+	SessionID    string `gorm:"size:64;uniqueIndex:idx_quarantined_chunk_seq"`
+	Sequence     int    `gorm:"uniqueIndex:idx_quarantined_chunk_seq"`
+	QuestionID   string
+	Reason       string
+	Data         json.RawMessage `gorm:"type:jsonb"`
+}
+
+// InteractionChunkPayload is a single NDJSON line posted to /metrics.
+type InteractionChunkPayload struct {
+	AssessmentID uint    `json:"assessmentId"`
+	SessionID    string  `json:"sessionId"`
+	Sequence     int     `json:"seq"`
+	SessionStart float64 `json:"sessionStart"`
+	QuestionID   string  `json:"questionId,omitempty"`
+	InteractionData
+}
+
+// InteractionEventStream holds the raw interaction events of one question of an
+// assessment, encoded with the eventstream package. Timestamps are relative to
+// Origin so that late chunks can be folded in on the same clock.
+type InteractionEventStream struct {
+	ID                 uint `gorm:"primarykey"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	AssessmentID       uint            `gorm:"uniqueIndex:idx_event_stream_question"`
+	Assessment         AssessmentState `gorm:"foreignKey:AssessmentID"`
+	QuestionID         string          `gorm:"uniqueIndex:idx_event_stream_question"` // Empty for events outside any question
+	Origin             float64         // Epoch milliseconds that timestamps are relative to
+	Encoding           int
+	MovementCount      int
+	InteractionCount   int
+	KeyboardEventCount int
+	GestureCount       int
+	PageEventCount     int
+	Data               []byte
+}
@@ -12,43 +12,138 @@ type MetricResult struct {
 
 type AssessmentMetric struct {
 	gorm.Model
-	AssessmentID uint
-	Assessment   AssessmentState `gorm:"foreignKey:AssessmentID"`
-	QuestionID   string
-	MetricKey    string
-	MetricValue  float64
-	SampleSize   int
+	AssessmentID     uint
+	Assessment       AssessmentState `gorm:"foreignKey:AssessmentID"`
+	QuestionID       string
+	MetricKey        string
+	MetricValue      float64
+	SampleSize       int
+	AlgorithmVersion int `gorm:"default:1"`
 }
 
 type InteractionData struct {
 	MouseMovements    []MouseMovement    `json:"movements"`
 	MouseInteractions []MouseInteraction `json:"interactions"`
 	KeyboardEvents    []KeyboardEvent    `json:"keyboardEvents"`
+	TouchGestures     []TouchGesture     `json:"gestures,omitempty"`
+	PageEvents        []PageEvent        `json:"pageEvents,omitempty"`
 	StartTime         float64            `json:"startTime"`
 }
 
+// Pointer types reported by the browser's Pointer Events API. Data recorded before
+// pointer types were captured has none and is treated as mouse input.
+const (
+	PointerMouse = "mouse"
+	PointerPen   = "pen"
+	PointerTouch = "touch"
+)
+
+// Pointer describes the input device behind a movement or click. Pressure is
+// normalised to 0-1 and the contact size is in CSS pixels.
+type Pointer struct {
+	PointerType   string  `json:"pointerType,omitempty"`
+	Pressure      float64 `json:"pressure,omitempty"`
+	ContactWidth  float64 `json:"contactWidth,omitempty"`
+	ContactHeight float64 `json:"contactHeight,omitempty"`
+}
+
+// IsTouch reports whether the pointer was a finger on a touch screen.
+func (p Pointer) IsTouch() bool {
+	return p.PointerType == PointerTouch
+}
+
 type MouseMovement struct {
 	X          float64 `json:"x"`
 	Y          float64 `json:"y"`
 	Timestamp  float64 `json:"timestamp"`
 	TargetID   string  `json:"targetId,omitempty"`
 	QuestionID string  `json:"questionId,omitempty"`
+	Pointer
 }
 
 type MouseInteraction struct {
-	TargetID   string  `json:"targetId"`
-	TargetType string  `json:"targetType"`
+	TargetID     string  `json:"targetId"`
+	TargetType   string  `json:"targetType"`
+	QuestionID   string  `json:"questionId,omitempty"`
+	ClickX       float64 `json:"clickX"`
+	ClickY       float64 `json:"clickY"`
+	TargetX      float64 `json:"targetX"`
+	TargetY      float64 `json:"targetY"`
+	TargetWidth  float64 `json:"targetWidth,omitempty"`
+	TargetHeight float64 `json:"targetHeight,omitempty"`
+	Timestamp    float64 `json:"timestamp"`
+	Duration     float64 `json:"duration,omitempty"` // Milliseconds between pointer down and up
+	Pointer
+}
+
+// Touch gesture types recognised by the client.
+const (
+	GestureTap   = "tap"
+	GestureSwipe = "swipe"
+)
+
+// TouchGesture is one touch from first contact to release. PointerCount is the
+// largest number of fingers on the screen at once during the gesture.
+type TouchGesture struct {
+	Type         string  `json:"type"`
+	QuestionID   string  `json:"questionId,omitempty"`
+	TargetID     string  `json:"targetId,omitempty"`
+	StartX       float64 `json:"startX"`
+	StartY       float64 `json:"startY"`
+	EndX         float64 `json:"endX"`
+	EndY         float64 `json:"endY"`
+	Timestamp    float64 `json:"timestamp"` // Time of first contact
+	Duration     float64 `json:"duration"`
+	PointerCount int     `json:"pointerCount"`
+	Pointer
+}
+
+// Page event types.
+const (
+	PageQuestionShown  = "question_shown"  // A question appeared on screen
+	PageQuestionLeft   = "question_left"   // The user submitted or navigated away from a question
+	PageOptionSelected = "option_selected" // A radio or drop-down option was chosen; TargetID names the option
+	PageHidden         = "hidden"          // The tab was hidden (visibilitychange)
+	PageVisible        = "visible"         // The tab became visible again, or was visible when a question was shown
+	PageBlur           = "blur"            // The window lost focus
+	PageFocus          = "focus"           // The window regained focus, or had it when a question was shown
+	PageResize         = "resize"          // The window was resized; Width and Height hold the new viewport size
+	PageScroll         = "scroll"          // The page was scrolled; Value holds the new vertical scroll offset
+)
+
+// PageEvent records something that happened to the page rather than a pointer or
+// key, such as a question being shown, an answer option being selected or the tab
+// being hidden.
+type PageEvent struct {
+	Type       string  `json:"type"`
 	QuestionID string  `json:"questionId,omitempty"`
-	ClickX     float64 `json:"clickX"`
-	ClickY     float64 `json:"clickY"`
-	TargetX    float64 `json:"targetX"`
-	TargetY    float64 `json:"targetY"`
+	TargetID   string  `json:"targetId,omitempty"`
 	Timestamp  float64 `json:"timestamp"`
+	Value      float64 `json:"value,omitempty"`
+	Width      float64 `json:"width,omitempty"`
+	Height     float64 `json:"height,omitempty"`
 }
 
+// Key categories. With category-only keystroke capture these are all that is
+// known about a key; the key itself never reaches the server.
+const (
+	KeyCategoryCharacter  = "character"
+	KeyCategorySpace      = "space" // Space and Enter
+	KeyCategoryBackspace  = "backspace"
+	KeyCategoryDelete     = "delete"
+	KeyCategoryNavigation = "navigation"
+	KeyCategoryModifier   = "modifier"
+	KeyCategoryOther      = "other"
+)
+
+// KeyboardEvent is one key press or release. Key is empty when keystrokes are
+// captured as categories only. PressID pairs a keyup with its keydown without
+// revealing which key was pressed.
 type KeyboardEvent struct {
 	Type       string  `json:"type"`
-	Key        string  `json:"key"`
+	Key        string  `json:"key,omitempty"`
+	Category   string  `json:"category,omitempty"`
+	PressID    int     `json:"pressId,omitempty"`
 	IsModifier bool    `json:"isModifier"`
 	Timestamp  float64 `json:"timestamp"`
 	QuestionID string  `json:"questionId,omitempty"`
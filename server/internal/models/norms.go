@@ -0,0 +1,57 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// NormativeEntry is one row of a normative table: the mean and standard deviation
+// of a cognitive test metric in a reference group. Groups are stratified by age,
+// years of education and sex; a zero bound or empty sex leaves that side open.
+type NormativeEntry struct {
+	gorm.Model
+	Test          string `gorm:"index:idx_norm_metric"` // Cognitive test type, e.g. "tmt"
+	MetricKey     string `gorm:"index:idx_norm_metric"` // Chart metric key, e.g. "part_b_time"
+	AgeMin        int    // Inclusive
+	AgeMax        int    // Inclusive
+	EducationMin  int    // Inclusive, in years
+	EducationMax  int    // Inclusive, in years
+	Sex           string `gorm:"type:varchar(16)"`
+	Mean          float64
+	SD            float64
+	N             int
+	LowerIsBetter bool // e.g. completion times and error counts
+	Source        string
+}
+
+// GroupLabel describes the reference group, e.g. "Age 25-34, 13+ years of education, female".
+func (e *NormativeEntry) GroupLabel() string {
+	parts := make([]string, 0, 3)
+	if label := rangeLabel(e.AgeMin, e.AgeMax); label != "" {
+		parts = append(parts, "Age "+label)
+	}
+	if label := rangeLabel(e.EducationMin, e.EducationMax); label != "" {
+		parts = append(parts, label+" years of education")
+	}
+	if e.Sex != "" {
+		parts = append(parts, e.Sex)
+	}
+	if len(parts) == 0 {
+		return "All adults"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func rangeLabel(min, max int) string {
+	switch {
+	case min == 0 && max == 0:
+		return ""
+	case max == 0:
+		return fmt.Sprintf("%d+", min)
+	case min == 0:
+		return fmt.Sprintf("up to %d", max)
+	}
+	return fmt.Sprintf("%d-%d", min, max)
+}
@@ -0,0 +1,9 @@
+package models
+
+// Provenance records where a cognitive test result came from: the version of the
+// scoring algorithm that produced it and, for results written by the reprocess
+// command, the result it was recomputed from. Original rows are never modified.
+type Provenance struct {
+	AlgorithmVersion  int   `gorm:"default:1;index"`
+	ReprocessedFromID *uint `gorm:"index"`
+}
@@ -17,6 +17,8 @@ type TMTResult struct {
 	PartBCompletionTime float64
 	PartBErrors         int
 	BToARatio           float64
+	Validity            `gorm:"embedded"`
+	Provenance          `gorm:"embedded"`
 	RawData             json.RawMessage `gorm:"type:jsonb"`
 	CreatedAt           time.Time
 }
@@ -31,4 +33,5 @@ type TMTClick struct {
 	Time        float64
 	TargetItem  int
 	CurrentPart string
+	IsPractice  bool `gorm:"default:false"` // Practice clicks are stored but never scored
 }
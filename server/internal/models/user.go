@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -14,6 +16,35 @@ type User struct {
 	EmailNotificationsEnabled bool   `gorm:"default:false"`
 	ReminderTime              string `gorm:"type:varchar(5);default:'09:00'"` // Default to a common local time
 	TimeZone                  string `gorm:"default:'UTC'"`                   // e.g., "America/New_York"
+	Demographics              `gorm:"embedded"`
+}
+
+// Sex values used by Demographics and normative tables.
+const (
+	SexFemale = "female"
+	SexMale   = "male"
+)
+
+// Demographics are the optional details used to compare a user's cognitive test
+// scores with normative data for people like them.
+type Demographics struct {
+	DateOfBirth    *time.Time `gorm:"type:date"`
+	EducationYears *int
+	Sex            string `gorm:"type:varchar(16)"`
+}
+
+// AgeAt returns the user's age in whole years at the given time, or false if their
+// date of birth is unknown.
+func (d Demographics) AgeAt(t time.Time) (int, bool) {
+	if d.DateOfBirth == nil {
+		return 0, false
+	}
+	dob := *d.DateOfBirth
+	age := t.Year() - dob.Year()
+	if t.Month() < dob.Month() || (t.Month() == dob.Month() && t.Day() < dob.Day()) {
+		age--
+	}
+	return age, true
 }
 
 func (u *User) CheckPassword(password string) bool {
@@ -0,0 +1,15 @@
+package models
+
+import "github.com/lib/pq"
+
+const (
+	ValidityValid   = "valid"
+	ValidityInvalid = "invalid"
+)
+
+// Validity records whether a cognitive test run is trustworthy enough to chart.
+// It is embedded in each test's result row.
+type Validity struct {
+	ValidityStatus  string         `gorm:"type:varchar(16);default:'valid';index"`
+	ValidityReasons pq.StringArray `gorm:"type:text[]"`
+}
@@ -0,0 +1,283 @@
+// server/internal/norms/norms.go
+package norms
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"crapp-go/internal/cognitive"
+	"crapp-go/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// row is one normative group as written in an import file. CSV headers use the
+// same names as the YAML keys.
+type row struct {
+	Test          string  `yaml:"test"`
+	Metric        string  `yaml:"metric"`
+	AgeMin        int     `yaml:"age_min"`
+	AgeMax        int     `yaml:"age_max"`
+	EducationMin  int     `yaml:"education_min"`
+	EducationMax  int     `yaml:"education_max"`
+	Sex           string  `yaml:"sex"`
+	Mean          float64 `yaml:"mean"`
+	SD            float64 `yaml:"sd"`
+	N             int     `yaml:"n"`
+	LowerIsBetter bool    `yaml:"lower_is_better"`
+	Source        string  `yaml:"source"`
+}
+
+// yamlFile is the layout of a YAML import file. A top-level source applies to
+// every row that does not name its own.
+type yamlFile struct {
+	Source string `yaml:"source"`
+	Norms  []row  `yaml:"norms"`
+}
+
+// Load reads a normative table from a .csv, .yaml or .yml file and checks every
+// row against the registered cognitive tests.
+func Load(path string) ([]models.NormativeEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []row
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		rows, err = parseCSV(f)
+	case ".yaml", ".yml":
+		rows, err = parseYAML(f)
+	default:
+		return nil, fmt.Errorf("norms: unsupported file type %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.NormativeEntry, 0, len(rows))
+	for i, r := range rows {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("norms: row %d: %w", i+1, err)
+		}
+		entries = append(entries, models.NormativeEntry{
+			Test:          r.Test,
+			MetricKey:     r.Metric,
+			AgeMin:        r.AgeMin,
+			AgeMax:        r.AgeMax,
+			EducationMin:  r.EducationMin,
+			EducationMax:  r.EducationMax,
+			Sex:           strings.ToLower(r.Sex),
+			Mean:          r.Mean,
+			SD:            r.SD,
+			N:             r.N,
+			LowerIsBetter: r.LowerIsBetter,
+			Source:        r.Source,
+		})
+	}
+	return entries, nil
+}
+
+func parseYAML(r io.Reader) ([]row, error) {
+	var file yamlFile
+	if err := yaml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("norms: %w", err)
+	}
+	for i := range file.Norms {
+		if file.Norms[i].Source == "" {
+			file.Norms[i].Source = file.Source
+		}
+	}
+	return file.Norms, nil
+}
+
+func parseCSV(r io.Reader) ([]row, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("norms: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	column := make(map[string]int, len(header))
+	for i, name := range header {
+		column[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	for _, required := range []string{"test", "metric", "mean", "sd"} {
+		if _, ok := column[required]; !ok {
+			return nil, fmt.Errorf("norms: missing %q column", required)
+		}
+	}
+
+	rows := make([]row, 0, len(records)-1)
+	for line, record := range records[1:] {
+		field := func(name string) string {
+			if i, ok := column[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		var parseErr error
+		number := func(name string) float64 {
+			v := field(name)
+			if v == "" || parseErr != nil {
+				return 0
+			}
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				parseErr = fmt.Errorf("norms: line %d: invalid %s %q", line+2, name, v)
+			}
+			return n
+		}
+
+		r := row{
+			Test:         field("test"),
+			Metric:       field("metric"),
+			AgeMin:       int(number("age_min")),
+			AgeMax:       int(number("age_max")),
+			EducationMin: int(number("education_min")),
+			EducationMax: int(number("education_max")),
+			Sex:          field("sex"),
+			Mean:         number("mean"),
+			SD:           number("sd"),
+			N:            int(number("n")),
+			Source:       field("source"),
+		}
+		if v := field("lower_is_better"); v != "" {
+			if r.LowerIsBetter, err = strconv.ParseBool(v); err != nil {
+				parseErr = fmt.Errorf("norms: line %d: invalid lower_is_better %q", line+2, v)
+			}
+		}
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
+
+func (r row) validate() error {
+	test, ok := cognitive.Get(r.Test)
+	if !ok {
+		return fmt.Errorf("unknown test %q", r.Test)
+	}
+	if !slices.ContainsFunc(test.Metrics(), func(m models.MetricOption) bool { return m.Value == r.Metric }) {
+		return fmt.Errorf("test %q has no metric %q", r.Test, r.Metric)
+	}
+	if r.SD <= 0 {
+		return fmt.Errorf("standard deviation must be positive")
+	}
+	if r.AgeMax != 0 && r.AgeMax < r.AgeMin {
+		return fmt.Errorf("age_max is below age_min")
+	}
+	if r.EducationMax != 0 && r.EducationMax < r.EducationMin {
+		return fmt.Errorf("education_max is below education_min")
+	}
+	switch strings.ToLower(r.Sex) {
+	case "", models.SexFemale, models.SexMale:
+	default:
+		return fmt.Errorf("unknown sex %q", r.Sex)
+	}
+	return nil
+}
+
+// Match returns the most specific entry whose group includes a person with the
+// given demographics at the time of the test, or nil if none does. Groups limited
+// by sex beat open ones, then narrower age and education ranges win. A person
+// whose age, education or sex is unknown only matches groups open on that side.
+func Match(entries []models.NormativeEntry, d models.Demographics, takenAt time.Time) *models.NormativeEntry {
+	age, knowsAge := d.AgeAt(takenAt)
+
+	var best *models.NormativeEntry
+	for i := range entries {
+		e := &entries[i]
+		if !inRange(e.AgeMin, e.AgeMax, age, knowsAge) {
+			continue
+		}
+		education, knowsEducation := 0, d.EducationYears != nil
+		if knowsEducation {
+			education = *d.EducationYears
+		}
+		if !inRange(e.EducationMin, e.EducationMax, education, knowsEducation) {
+			continue
+		}
+		if e.Sex != "" && e.Sex != d.Sex {
+			continue
+		}
+		if best == nil || moreSpecific(e, best) {
+			best = e
+		}
+	}
+	return best
+}
+
+// inRange reports whether v lies within [min, max], where a zero bound is open.
+// An unknown value only lies within a range open on both sides.
+func inRange(min, max, v int, known bool) bool {
+	if !known {
+		return min == 0 && max == 0
+	}
+	return v >= min && (max == 0 || v <= max)
+}
+
+func moreSpecific(a, b *models.NormativeEntry) bool {
+	if (a.Sex != "") != (b.Sex != "") {
+		return a.Sex != ""
+	}
+	if wa, wb := width(a.AgeMin, a.AgeMax), width(b.AgeMin, b.AgeMax); wa != wb {
+		return wa < wb
+	}
+	return width(a.EducationMin, a.EducationMax) < width(b.EducationMin, b.EducationMax)
+}
+
+// width is the size of the range [min, max]. An open range is wider than any
+// closed one, but one with a lower bound is still narrower than one with none.
+func width(min, max int) int {
+	if max == 0 {
+		return math.MaxInt - min
+	}
+	return max - min
+}
+
+// Score is a raw score placed in its normative group. Z and StandardScore are
+// oriented so that higher always means better performance.
+type Score struct {
+	Z             float64
+	Percentile    float64 // 0-100
+	StandardScore float64 // Mean 100, SD 15
+}
+
+// Compute places a raw value in the entry's normative distribution, assuming
+// scores in the group are normally distributed.
+func Compute(entry *models.NormativeEntry, value float64) Score {
+	z := (value - entry.Mean) / entry.SD
+	if entry.LowerIsBetter {
+		z = (entry.Mean - value) / entry.SD
+	}
+	return Score{
+		Z:             z,
+		Percentile:    50 * math.Erfc(-z/math.Sqrt2),
+		StandardScore: 100 + 15*z,
+	}
+}
+
+// NormedResult is one test result with its place in the matching normative group.
+// Group and Score are nil when no group matches the user.
+type NormedResult struct {
+	Date  time.Time
+	Value float64
+	Group *models.NormativeEntry
+	Score *Score
+}
@@ -0,0 +1,176 @@
+package norms
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"crapp-go/internal/models"
+)
+
+// writeFile writes content to a file with the given name in a fresh directory.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCSV(t *testing.T) {
+	path := writeFile(t, "norms.csv", `test,metric,age_min,age_max,education_min,education_max,sex,mean,sd,n,lower_is_better,source
+tmt,part_b_time,18,39,12,16,Female,54300.5,12100,120,true,Tombaugh 2004
+cpt,detection_rate,,,,,,92,6.5,,,
+`)
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.NormativeEntry{
+		{Test: "tmt", MetricKey: "part_b_time", AgeMin: 18, AgeMax: 39, EducationMin: 12, EducationMax: 16, Sex: models.SexFemale,
+			Mean: 54300.5, SD: 12100, N: 120, LowerIsBetter: true, Source: "Tombaugh 2004"},
+		{Test: "cpt", MetricKey: "detection_rate", Mean: 92, SD: 6.5},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Load returned %d entries, want %d", len(entries), len(want))
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestLoadYAML(t *testing.T) {
+	path := writeFile(t, "norms.yml", `source: Shared study
+norms:
+  - test: tmt
+    metric: part_a_time
+    age_min: 40
+    mean: 31000
+    sd: 9000
+    lower_is_better: true
+  - test: tmt
+    metric: part_a_time
+    sex: male
+    mean: 30000
+    sd: 8000
+    source: Own study
+`)
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Load returned %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.AgeMin != 40 || e.AgeMax != 0 || !e.LowerIsBetter || e.Source != "Shared study" {
+		t.Errorf("first entry = %+v, want the file's source and an open upper age", e)
+	}
+	if e := entries[1]; e.Sex != models.SexMale || e.Source != "Own study" {
+		t.Errorf("second entry = %+v, want its own source", e)
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	const header = "test,metric,age_min,age_max,sex,mean,sd,lower_is_better\n"
+	tests := []struct {
+		name, file, content, wantErr string
+	}{
+		{"file type", "norms.txt", "", "unsupported file type"},
+		{"missing column", "norms.csv", "test,metric,mean\ntmt,part_a_time,1\n", `missing "sd" column`},
+		{"invalid number", "norms.csv", header + "tmt,part_a_time,,,,fast,1,\n", "line 2: invalid mean"},
+		{"invalid boolean", "norms.csv", header + "tmt,part_a_time,,,,1,1,maybe\n", "invalid lower_is_better"},
+		{"unknown test", "norms.csv", header + "stroop,part_a_time,,,,1,1,\n", `unknown test "stroop"`},
+		{"unknown metric", "norms.csv", header + "tmt,reaction_time,,,,1,1,\n", `has no metric "reaction_time"`},
+		{"zero sd", "norms.csv", header + "tmt,part_a_time,,,,1,0,\n", "row 1: standard deviation"},
+		{"age range", "norms.csv", header + "tmt,part_a_time,60,40,,1,1,\n", "age_max is below age_min"},
+		{"sex", "norms.csv", header + "tmt,part_a_time,,,other,1,1,\n", `unknown sex "other"`},
+		{"yaml", "norms.yaml", "norms: [", "norms:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeFile(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	entries := []models.NormativeEntry{
+		{Source: "everyone"},
+		{Source: "18-39", AgeMin: 18, AgeMax: 39},
+		{Source: "18-29", AgeMin: 18, AgeMax: 29},
+		{Source: "40+", AgeMin: 40},
+		{Source: "female 18-39", AgeMin: 18, AgeMax: 39, Sex: models.SexFemale},
+		{Source: "18-29 with 12-16 years of education", AgeMin: 18, AgeMax: 29, EducationMin: 12, EducationMax: 16},
+	}
+	takenAt := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	bornYearsBefore := func(years int) *time.Time {
+		dob := takenAt.AddDate(-years, 0, 0)
+		return &dob
+	}
+	years := func(n int) *int { return &n }
+
+	tests := []struct {
+		name         string
+		demographics models.Demographics
+		want         string
+	}{
+		{"nothing known", models.Demographics{}, "everyone"},
+		{"narrowest age range", models.Demographics{DateOfBirth: bornYearsBefore(25)}, "18-29"},
+		{"birthday later this year", models.Demographics{DateOfBirth: func() *time.Time { d := takenAt.AddDate(-30, 0, 1); return &d }()}, "18-29"},
+		{"open upper bound", models.Demographics{DateOfBirth: bornYearsBefore(70)}, "40+"},
+		{"sex beats a narrower age range", models.Demographics{DateOfBirth: bornYearsBefore(25), Sex: models.SexFemale}, "female 18-39"},
+		{"other sex skips the female group", models.Demographics{DateOfBirth: bornYearsBefore(35), Sex: models.SexMale}, "18-39"},
+		{"education narrows the match", models.Demographics{DateOfBirth: bornYearsBefore(25), EducationYears: years(14)}, "18-29 with 12-16 years of education"},
+		{"education outside the range", models.Demographics{DateOfBirth: bornYearsBefore(25), EducationYears: years(20)}, "18-29"},
+		{"unknown age only matches open groups", models.Demographics{Sex: models.SexFemale}, "everyone"},
+		{"under every age range", models.Demographics{DateOfBirth: bornYearsBefore(10)}, "everyone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Match(entries, tt.demographics, takenAt)
+			if got == nil || got.Source != tt.want {
+				t.Errorf("Match = %+v, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := Match(entries[1:2], models.Demographics{}, takenAt); got != nil {
+		t.Errorf("Match with an unknown age and only age-limited groups = %+v, want nil", got)
+	}
+	if got := Match(nil, models.Demographics{}, takenAt); got != nil {
+		t.Errorf("Match with no entries = %+v, want nil", got)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name           string
+		entry          models.NormativeEntry
+		value          float64
+		wantZ          float64
+		wantPercentile float64
+	}{
+		{"at the mean", models.NormativeEntry{Mean: 50, SD: 10}, 50, 0, 50},
+		{"one SD above", models.NormativeEntry{Mean: 50, SD: 10}, 60, 1, 84.13447460685429},
+		{"two SD below", models.NormativeEntry{Mean: 50, SD: 10}, 30, -2, 2.275013194817921},
+		{"faster than the mean when lower is better", models.NormativeEntry{Mean: 30000, SD: 8000, LowerIsBetter: true}, 22000, 1, 84.13447460685429},
+		{"slower than the mean when lower is better", models.NormativeEntry{Mean: 30000, SD: 8000, LowerIsBetter: true}, 42000, -1.5, 6.680720126885807},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(&tt.entry, tt.value)
+			if math.Abs(got.Z-tt.wantZ) > 1e-12 || math.Abs(got.Percentile-tt.wantPercentile) > 1e-9 || math.Abs(got.StandardScore-(100+15*tt.wantZ)) > 1e-9 {
+				t.Errorf("Compute = %+v, want Z %v, percentile %v, standard score %v", got, tt.wantZ, tt.wantPercentile, 100+15*tt.wantZ)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"math/rand"
 	"time"
 
@@ -64,6 +65,16 @@ func GetMostRecentAssessmentState(userID uint) (*models.AssessmentState, error)
 	return &state, err
 }
 
+// GetUserAssessmentState loads an assessment only if it belongs to the given user.
+func GetUserAssessmentState(ctx context.Context, assessmentID uint, userID uint) (*models.AssessmentState, error) {
+	var state models.AssessmentState
+	err := database.DB.WithContext(ctx).Where("id = ? AND user_id = ?", assessmentID, userID).First(&state).Error
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
 func UpdateAssessmentIndex(assessmentID uint, newIndex int) error {
 	return database.DB.Model(&models.AssessmentState{}).Where("id = ?", assessmentID).Update("current_question_index", newIndex).Error
 }
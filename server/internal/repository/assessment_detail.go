@@ -0,0 +1,93 @@
+// server/internal/repository/assessment_detail.go
+package repository
+
+import (
+	"context"
+	"crapp-go/internal/cognitive"
+	"crapp-go/internal/database"
+	"crapp-go/internal/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AssessmentMetric is one value recorded for a question of an assessment.
+type AssessmentMetric struct {
+	QuestionID     string
+	MetricKey      string
+	Value          float64
+	ValidityStatus string
+}
+
+// GetAssessmentMetrics returns every charted value of one of a user's assessments:
+// interaction metrics, symptom scores and cognitive test metrics, each from its
+// latest computation.
+func GetAssessmentMetrics(ctx context.Context, userID int, assessmentID uint) ([]AssessmentMetric, error) {
+	var values []AssessmentMetric
+	query := fmt.Sprintf(`
+		%s
+		SELECT question_id, metric_key, metric_value AS value, validity_status
+		FROM all_metrics
+		WHERE assessment_id = ? AND recompute_rank = 1 AND metric_value IS NOT NULL
+		ORDER BY question_id, metric_key;
+	`, getMetricsCTE())
+
+	err := database.DB.WithContext(ctx).Raw(query, userID, assessmentID).Scan(&values).Error
+	return values, err
+}
+
+// GetResultValidity returns the validity of the latest result of a test in an
+// assessment, or nil if the test was not run.
+func GetResultValidity(ctx context.Context, test cognitive.CognitiveTest, assessmentID uint) (*models.Validity, error) {
+	var validity models.Validity
+	err := database.DB.WithContext(ctx).
+		Table(test.ResultsTable()).
+		Select("validity_status, validity_reasons").
+		Where("assessment_id = ? AND deleted_at IS NULL", assessmentID).
+		Order("id DESC").
+		Take(&validity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &validity, nil
+}
+
+// GetCPTEvents returns the scored stimuli and responses of the latest CPT result
+// of an assessment, in the order they were recorded.
+func GetCPTEvents(ctx context.Context, assessmentID uint) ([]models.CPTEvent, error) {
+	var events []models.CPTEvent
+	err := latestTrials(ctx, "cpt_results", assessmentID).Find(&events).Error
+	return events, err
+}
+
+// GetDSTAttempts returns the scored trials of the latest Digit Span result of an
+// assessment, in the order they were recorded.
+func GetDSTAttempts(ctx context.Context, assessmentID uint) ([]models.DSTAttempt, error) {
+	var attempts []models.DSTAttempt
+	err := latestTrials(ctx, "dst_results", assessmentID).Find(&attempts).Error
+	return attempts, err
+}
+
+// GetTMTClicks returns the scored clicks of the latest Trail Making result of an
+// assessment, in the order they were recorded.
+func GetTMTClicks(ctx context.Context, assessmentID uint) ([]models.TMTClick, error) {
+	var clicks []models.TMTClick
+	err := latestTrials(ctx, "tmt_results", assessmentID).Find(&clicks).Error
+	return clicks, err
+}
+
+// latestTrials scopes a trial query to the non-practice rows of the latest result
+// in resultsTable for the assessment.
+func latestTrials(ctx context.Context, resultsTable string, assessmentID uint) *gorm.DB {
+	latest := fmt.Sprintf(`result_id = (
+		SELECT id FROM %s WHERE assessment_id = ? AND deleted_at IS NULL ORDER BY id DESC LIMIT 1
+	)`, resultsTable)
+	return database.DB.WithContext(ctx).
+		Where(latest, assessmentID).
+		Where("is_practice = ?", false).
+		Order("id")
+}
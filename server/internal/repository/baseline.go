@@ -0,0 +1,97 @@
+// server/internal/repository/baseline.go
+package repository
+
+import (
+	"context"
+	"crapp-go/internal/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetBaseline returns a user's stored baseline for a metric, or nil if none has
+// been computed yet.
+func GetBaseline(ctx context.Context, userID int, questionID, metricKey string) (*models.Baseline, error) {
+	var baseline models.Baseline
+	err := conn(ctx).
+		Where("user_id = ? AND question_id = ? AND metric_key = ?", userID, questionID, metricKey).
+		First(&baseline).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &baseline, nil
+}
+
+// GetBaselines returns every baseline stored for a user.
+func GetBaselines(ctx context.Context, userID int) ([]models.Baseline, error) {
+	var baselines []models.Baseline
+	err := conn(ctx).Where("user_id = ?", userID).Find(&baselines).Error
+	return baselines, err
+}
+
+// ComputeBaselineStats fills in the mean, standard deviation and count of the
+// valid values inside the baseline's window. Assessments marked invalid are left
+// out.
+func ComputeBaselineStats(ctx context.Context, baseline *models.Baseline) error {
+	version, versionArgs := versionClause("am", baseline.AlgorithmVersion)
+	args := append([]interface{}{baseline.UserID, baseline.UserID, baseline.QuestionID, baseline.MetricKey, models.ValidityValid}, versionArgs...)
+
+	var window string
+	switch baseline.Method {
+	case models.BaselineFirstN:
+		window = "ORDER BY am.created_at LIMIT ?"
+		args = append(args, baseline.FirstN)
+	case models.BaselineDateRange:
+		if baseline.StartDate == nil || baseline.EndDate == nil {
+			return errors.New("baseline date range needs a start and an end date")
+		}
+		window = "AND am.created_at >= ? AND am.created_at < ?"
+		args = append(args, *baseline.StartDate, baseline.EndDate.AddDate(0, 0, 1)) // The end date is inclusive
+	default:
+		return fmt.Errorf("unknown baseline method %q", baseline.Method)
+	}
+
+	query := fmt.Sprintf(`
+		%s
+		SELECT
+			COALESCE(AVG(metric_value), 0) AS mean,
+			COALESCE(STDDEV_SAMP(metric_value), 0) AS sd,
+			COUNT(metric_value) AS n
+		FROM (
+			SELECT am.metric_value
+			FROM all_metrics am
+			JOIN assessment_states a ON am.assessment_id = a.id
+			WHERE a.user_id = ? AND am.question_id = ? AND am.metric_key = ? AND am.validity_status = ?
+				AND %s AND a.is_complete = true AND a.invalidated_at IS NULL AND am.metric_value IS NOT NULL
+			%s
+		) AS windowed;
+	`, getMetricsCTE(), version, window)
+
+	var stats struct {
+		Mean float64
+		SD   float64
+		N    int
+	}
+	if err := conn(ctx).Raw(query, args...).Scan(&stats).Error; err != nil {
+		return err
+	}
+	baseline.Mean, baseline.SD, baseline.N = stats.Mean, stats.SD, stats.N
+	return nil
+}
+
+// SaveBaseline stores a baseline, replacing the user's previous baseline for the metric.
+func SaveBaseline(ctx context.Context, baseline *models.Baseline) error {
+	return conn(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "question_id"}, {Name: "metric_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"updated_at", "method", "first_n", "start_date", "end_date", "algorithm_version", "mean", "sd", "n",
+			}),
+		}).
+		Create(baseline).Error
+}
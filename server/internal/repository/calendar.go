@@ -0,0 +1,57 @@
+// server/internal/repository/calendar.go
+package repository
+
+import (
+	"context"
+	"crapp-go/internal/database"
+	"fmt"
+	"time"
+)
+
+// CalendarDay summarises a user's completed assessments on one local day.
+type CalendarDay struct {
+	Day          time.Time
+	Assessments  int
+	AssessmentID uint     // The day's latest completed assessment
+	Score        *float64 // Mean of the day's valid scores; nil if none were recorded
+}
+
+// GetCalendarDays returns one row per local day in the user's time zone, between
+// from and to inclusive, on which the user completed an assessment. Each day is
+// scored by the mean of the given series over its assessments, so several
+// symptom keys give a composite score, leaving out assessments marked invalid.
+// Days without an assessment are omitted.
+func GetCalendarDays(ctx context.Context, userID int, timeZone string, scoreKeys []SeriesKey, from, to time.Time) ([]CalendarDay, error) {
+	var days []CalendarDay
+	pairs := make([][]interface{}, 0, len(scoreKeys))
+	for _, key := range scoreKeys {
+		pairs = append(pairs, []interface{}{key.QuestionID, key.MetricKey})
+	}
+	scoreFilter := "FALSE"
+	args := []interface{}{}
+	if len(pairs) > 0 {
+		scoreFilter = "(score.question_id, score.metric_key) IN ?"
+		args = append(args, pairs)
+	}
+
+	query := fmt.Sprintf(`
+		%s
+		SELECT
+			(a.created_at AT TIME ZONE ?)::date AS day,
+			COUNT(DISTINCT a.id) AS assessments,
+			MAX(a.id) AS assessment_id,
+			AVG(score.metric_value) AS score
+		FROM assessment_states a
+		LEFT JOIN all_metrics score ON score.assessment_id = a.id AND %s
+			AND score.validity_status = 'valid' AND score.recompute_rank = 1 AND a.invalidated_at IS NULL
+		WHERE a.user_id = ? AND a.is_complete = true
+			AND (a.created_at AT TIME ZONE ?)::date BETWEEN ? AND ?
+		GROUP BY day
+		ORDER BY day;
+	`, getMetricsCTE(), scoreFilter)
+
+	args = append([]interface{}{userID, timeZone}, args...)
+	args = append(args, userID, timeZone, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	err := database.DB.WithContext(ctx).Raw(query, args...).Scan(&days).Error
+	return days, err
+}
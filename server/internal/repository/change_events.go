@@ -0,0 +1,40 @@
+// server/internal/repository/change_events.go
+package repository
+
+import (
+	"context"
+	"crapp-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ReplaceChangeEvents swaps a user's change events for one metric for a freshly
+// computed set in a single transaction.
+func ReplaceChangeEvents(ctx context.Context, userID int, questionID, metricKey string, events []models.ChangeEvent) error {
+	return conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().
+			Where("user_id = ? AND question_id = ? AND metric_key = ?", userID, questionID, metricKey).
+			Delete(&models.ChangeEvent{}).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		return tx.Create(&events).Error
+	})
+}
+
+// GetChangeEvents returns a user's change events, oldest first. An empty
+// questionID or metricKey matches every question or metric.
+func GetChangeEvents(ctx context.Context, userID int, questionID, metricKey string) ([]models.ChangeEvent, error) {
+	var events []models.ChangeEvent
+	query := conn(ctx).Where("user_id = ?", userID)
+	if questionID != "" {
+		query = query.Where("question_id = ?", questionID)
+	}
+	if metricKey != "" {
+		query = query.Where("metric_key = ?", metricKey)
+	}
+	err := query.Order("date").Find(&events).Error
+	return events, err
+}
@@ -3,38 +3,156 @@ package repository
 
 import (
 	"context"
+	"crapp-go/internal/cognitive"
 	"crapp-go/internal/database"
+	"crapp-go/internal/models"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type TimelineDataPoint struct {
-	Date  time.Time `json:"date"`
-	Value float64   `json:"value"`
+	QuestionID   string    `json:"questionId"`
+	MetricKey    string    `json:"metricKey"`
+	AssessmentID uint      `json:"assessmentId"` // The latest assessment of an aggregated point
+	Date         time.Time `json:"date"`         // The start of an aggregated point's period
+	Value        float64   `json:"value"`
+	Count        int       `json:"count"` // Assessments behind the point
 }
 
+// CorrelationDataPoint pairs the values of the two series on a correlation chart.
 type CorrelationDataPoint struct {
-	MetricValue  float64 `json:"metricValue"`
-	SymptomValue float64 `json:"symptomValue"`
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// SeriesKey identifies one charted series. A symptom score's metric key is its
+// question ID.
+type SeriesKey struct {
+	QuestionID string
+	MetricKey  string
+}
+
+// String encodes the key as "questionID:metricKey", the form used in URLs.
+func (k SeriesKey) String() string {
+	return k.QuestionID + ":" + k.MetricKey
+}
+
+// ParseSeriesKey decodes a key written by SeriesKey.String.
+func ParseSeriesKey(s string) (SeriesKey, bool) {
+	questionID, metricKey, ok := strings.Cut(s, ":")
+	if !ok || questionID == "" || metricKey == "" {
+		return SeriesKey{}, false
+	}
+	return SeriesKey{QuestionID: questionID, MetricKey: metricKey}, true
+}
+
+// SeriesValue is one value of a series, from one completed assessment.
+type SeriesValue struct {
+	AssessmentID uint
+	QuestionID   string
+	MetricKey    string
+	Value        float64
+}
+
+// Aggregation levels of a timeline series.
+const (
+	AggregateAssessment = "assessment" // One point per assessment
+	AggregateDay        = "day"
+	AggregateWeek       = "week" // Weeks start on Monday
+	AggregateMonth      = "month"
+)
+
+// Statistics that summarise the values of an aggregated period.
+const (
+	StatMean   = "mean"
+	StatMedian = "median"
+	StatMin    = "min"
+	StatMax    = "max"
+)
+
+var statisticSQL = map[string]string{
+	StatMean:   "AVG(am.metric_value)",
+	StatMedian: "percentile_cont(0.5) WITHIN GROUP (ORDER BY am.metric_value)",
+	StatMin:    "MIN(am.metric_value)",
+	StatMax:    "MAX(am.metric_value)",
+}
+
+// SeriesFilter narrows the rows that make up a chart series.
+type SeriesFilter struct {
+	ValidityStatus string
+	// AlgorithmVersion restricts the series to values computed by one version of
+	// the scoring algorithm. Zero selects the latest recomputation of every run.
+	AlgorithmVersion int
+
+	// From and To limit the series to local dates in TimeZone, inclusive. A zero
+	// date leaves that end open.
+	From, To time.Time
+	// Aggregation groups the values into local days, weeks or months in TimeZone,
+	// summarised by Statistic. Empty means one point per assessment.
+	Aggregation string
+	Statistic   string
+	TimeZone    string
+}
+
+// location returns the filter's time zone for SQL, defaulting to UTC.
+func (f SeriesFilter) location() string {
+	if f.TimeZone == "" {
+		return "UTC"
+	}
+	return f.TimeZone
+}
+
+// dateRangeClause returns the condition limiting a created_at column to the
+// filter's local date range.
+func (f SeriesFilter) dateRangeClause(column string) (string, []interface{}) {
+	clause := "TRUE"
+	var args []interface{}
+	if !f.From.IsZero() {
+		clause += fmt.Sprintf(" AND (%s AT TIME ZONE ?)::date >= ?", column)
+		args = append(args, f.location(), f.From.Format("2006-01-02"))
+	}
+	if !f.To.IsZero() {
+		clause += fmt.Sprintf(" AND (%s AT TIME ZONE ?)::date <= ?", column)
+		args = append(args, f.location(), f.To.Format("2006-01-02"))
+	}
+	return clause, args
 }
 
+// getMetricsCTE unions every charted value of one user into all_metrics. The
+// user's ID is the first argument of the query. A run may be scored more than
+// once (e.g. by the reprocess command), so each row is ranked against the other
+// computations of the same value: recompute_rank 1 is the newest computation
+// overall, version_rank 1 the newest within its algorithm version. Rows are
+// limited to the user's assessments before they are ranked, so the window
+// functions never sort other users' data.
 func getMetricsCTE() string {
-	return `
-	WITH all_metrics AS (
+	return fmt.Sprintf(`
+	WITH scoped_assessments AS (
+		SELECT id, created_at FROM assessment_states WHERE user_id = ?
+	),
+	raw_metrics AS (
 		-- Mouse and Keyboard Metrics
 		SELECT
+			m.id AS row_id,
 			m.assessment_id,
 			a.created_at,
 			m.question_id,
 			m.metric_key,
-			m.metric_value
+			m.metric_value,
+			'valid' AS validity_status,
+			m.algorithm_version
 		FROM assessment_metrics m
-		JOIN assessment_states a ON m.assessment_id = a.id
+		JOIN scoped_assessments a ON m.assessment_id = a.id
+		WHERE m.deleted_at IS NULL
 		
 		UNION ALL
 		
 		-- Self-Reported Symptom Scores
 		SELECT 
+			ans.id AS row_id,
 			ans.assessment_id, 
 			a.created_at, 
 			ans.question_id, 
@@ -42,80 +160,250 @@ func getMetricsCTE() string {
 			CASE
 				WHEN ans.answer_value ~ '^[0-9\.]+$' THEN ans.answer_value::float
 				ELSE NULL
-			END as metric_value
+			END as metric_value,
+			'valid' AS validity_status,
+			1 AS algorithm_version
 		FROM answers ans
-		JOIN assessment_states a ON ans.assessment_id = a.id
+		JOIN scoped_assessments a ON ans.assessment_id = a.id
 		WHERE ans.question_id IN ('headache', 'cognitive', 'tinnitus', 'dizziness', 'visual', 'medication_changes')
 
 		UNION ALL
 
-		-- CPT Results
-		SELECT assessment_id, created_at, 'cpt' AS question_id, 'reaction_time' AS metric_key, average_reaction_time AS metric_value FROM cpt_results UNION ALL
-		SELECT assessment_id, created_at, 'cpt' AS question_id, 'detection_rate' AS metric_key, detection_rate AS metric_value FROM cpt_results UNION ALL
-		SELECT assessment_id, created_at, 'cpt' AS question_id, 'omission_error_rate' AS metric_key, omission_error_rate AS metric_value FROM cpt_results UNION ALL
-		SELECT assessment_id, created_at, 'cpt' AS question_id, 'commission_error_rate' AS metric_key, commission_error_rate AS metric_value FROM cpt_results
+		-- Cognitive Test Results
+		%s
+	),
+	all_metrics AS (
+		SELECT
+			rm.*,
+			ROW_NUMBER() OVER (PARTITION BY assessment_id, question_id, metric_key ORDER BY row_id DESC) AS recompute_rank,
+			ROW_NUMBER() OVER (PARTITION BY assessment_id, question_id, metric_key, algorithm_version ORDER BY row_id DESC) AS version_rank
+		FROM raw_metrics rm
+	)
+	`, cognitive.ChartSQL())
+}
 
-		UNION ALL
+// versionClause returns the all_metrics condition that picks one computation per
+// run: the latest overall, or the latest of the requested algorithm version.
+func versionClause(alias string, algorithmVersion int) (string, []interface{}) {
+	if algorithmVersion == 0 {
+		return alias + ".recompute_rank = 1", nil
+	}
+	return alias + ".algorithm_version = ? AND " + alias + ".version_rank = 1", []interface{}{algorithmVersion}
+}
 
-		-- TMT Results
-		SELECT assessment_id, created_at, 'tmt' AS question_id, 'part_a_time' AS metric_key, part_a_completion_time AS metric_value FROM tmt_results UNION ALL
-		SELECT assessment_id, created_at, 'tmt' AS question_id, 'part_b_time' AS metric_key, part_b_completion_time AS metric_value FROM tmt_results UNION ALL
-		SELECT assessment_id, created_at, 'tmt' AS question_id, 'part_a_errors' AS metric_key, part_a_errors::float AS metric_value FROM tmt_results UNION ALL
-		SELECT assessment_id, created_at, 'tmt' AS question_id, 'part_b_errors' AS metric_key, part_b_errors::float AS metric_value FROM tmt_results UNION ALL
-		SELECT assessment_id, created_at, 'tmt' AS question_id, 'b_a_ratio' AS metric_key, b_to_a_ratio AS metric_value FROM tmt_results
+// GetTimelineData returns the points of each of the given series, fetched in one
+// query and ordered by date. Only runs with the filter's validity status are
+// returned, so invalid runs stay off the chart unless they are asked for
+// explicitly; assessments marked invalid are always left out. Aggregated points
+// are grouped in SQL, with periods starting at local midnight in the filter's
+// time zone. Use SplitSeries to separate the series again.
+func GetTimelineData(ctx context.Context, userID int, keys []SeriesKey, filter SeriesFilter) ([]TimelineDataPoint, error) {
+	var data []TimelineDataPoint
+	if len(keys) == 0 {
+		return data, nil
+	}
+	pairs := make([][]interface{}, len(keys))
+	for i, key := range keys {
+		pairs[i] = []interface{}{key.QuestionID, key.MetricKey}
+	}
 
-		UNION ALL
+	version, versionArgs := versionClause("am", filter.AlgorithmVersion)
+	dateRange, dateRangeArgs := filter.dateRangeClause("am.created_at")
+	where := fmt.Sprintf(`
+		FROM all_metrics am
+		JOIN assessment_states a ON am.assessment_id = a.id
+		WHERE a.user_id = ? AND (am.question_id, am.metric_key) IN ? AND am.validity_status = ? AND %s AND %s
+			AND a.is_complete = true AND a.invalidated_at IS NULL AND am.metric_value IS NOT NULL`, version, dateRange)
+	whereArgs := append(append([]interface{}{userID, pairs, filter.ValidityStatus}, versionArgs...), dateRangeArgs...)
 
-		-- DST Results
-		SELECT assessment_id, created_at, 'dst' AS question_id, 'highest_span' AS metric_key, highest_span_achieved::float AS metric_value FROM dst_results UNION ALL
-		SELECT assessment_id, created_at, 'dst' AS question_id, 'correct_trials' AS metric_key, correct_trials::float AS metric_value FROM dst_results UNION ALL
-		SELECT assessment_id, created_at, 'dst' AS question_id, 'total_trials' AS metric_key, total_trials::float AS metric_value FROM dst_results
-	)
-	`
+	var query string
+	var args []interface{}
+	switch filter.Aggregation {
+	case "", AggregateAssessment:
+		query = fmt.Sprintf(`
+			%s
+			SELECT
+				am.question_id,
+				am.metric_key,
+				am.assessment_id,
+				am.created_at as date,
+				am.metric_value as value,
+				1 AS count
+			%s
+			ORDER BY am.created_at;
+		`, getMetricsCTE(), where)
+		args = append([]interface{}{userID}, whereArgs...)
+	case AggregateDay, AggregateWeek, AggregateMonth:
+		statistic, ok := statisticSQL[filter.Statistic]
+		if !ok {
+			return nil, fmt.Errorf("unknown statistic %q", filter.Statistic)
+		}
+		// The aggregation level is one of the constants above, never user input.
+		query = fmt.Sprintf(`
+			%s
+			SELECT
+				am.question_id,
+				am.metric_key,
+				MAX(am.assessment_id) AS assessment_id,
+				date_trunc('%s', am.created_at AT TIME ZONE ?) AT TIME ZONE ? AS date,
+				%s AS value,
+				COUNT(*) AS count
+			%s
+			GROUP BY am.question_id, am.metric_key, 4
+			ORDER BY date;
+		`, getMetricsCTE(), filter.Aggregation, statistic, where)
+		args = append([]interface{}{userID, filter.location(), filter.location()}, whereArgs...)
+	default:
+		return nil, fmt.Errorf("unknown aggregation %q", filter.Aggregation)
+	}
+
+	err := database.DB.WithContext(ctx).Raw(query, args...).Scan(&data).Error
+	return data, err
 }
 
-func GetTimelineData(ctx context.Context, userID int, taskID string, metricKey string) ([]TimelineDataPoint, error) {
-	var data []TimelineDataPoint
+// SplitSeries groups timeline points by series, keeping their order.
+func SplitSeries(points []TimelineDataPoint) map[SeriesKey][]TimelineDataPoint {
+	series := make(map[SeriesKey][]TimelineDataPoint)
+	for _, point := range points {
+		key := SeriesKey{QuestionID: point.QuestionID, MetricKey: point.MetricKey}
+		series[key] = append(series[key], point)
+	}
+	return series
+}
 
+// GetAlgorithmVersions lists the algorithm versions a user's values of a metric
+// have been computed with, oldest first.
+func GetAlgorithmVersions(ctx context.Context, userID int, taskID string, metricKey string) ([]int, error) {
+	var versions []int
 	query := fmt.Sprintf(`
 		%s
-		SELECT
-			am.created_at as date,
-			am.metric_value as value
+		SELECT DISTINCT am.algorithm_version
 		FROM all_metrics am
 		JOIN assessment_states a ON am.assessment_id = a.id
-		WHERE a.user_id = ? AND am.question_id = ? AND am.metric_key = ? AND a.is_complete = true
-		ORDER BY am.created_at;
+		WHERE a.user_id = ? AND am.question_id = ? AND am.metric_key = ?
+		ORDER BY am.algorithm_version;
 	`, getMetricsCTE())
 
-	err := database.DB.WithContext(ctx).Raw(query, userID, taskID, metricKey).Scan(&data).Error
+	err := database.DB.WithContext(ctx).Raw(query, userID, userID, taskID, metricKey).Scan(&versions).Error
+	return versions, err
+}
+
+// GetCorrelationData pairs the values of two series. With a lag of zero each pair
+// comes from the same assessment. Otherwise both series are averaged per local day
+// in the filter's time zone, the one its date range is in, and each day's x value
+// is paired with the y value lagDays later, e.g. today's reaction time with tomorrow's headache score.
+// Assessments marked invalid are left out.
+func GetCorrelationData(ctx context.Context, userID int, x, y SeriesKey, lagDays int, filter SeriesFilter) ([]CorrelationDataPoint, error) {
+	var data []CorrelationDataPoint
+	dateRange, dateRangeArgs := filter.dateRangeClause("a.created_at")
+	if lagDays == 0 {
+		query := fmt.Sprintf(`
+			%s
+			SELECT
+				x_series.metric_value AS x,
+				y_series.metric_value AS y
+			FROM
+				(
+					SELECT assessment_id, metric_value
+					FROM all_metrics
+					WHERE question_id = ? AND metric_key = ? AND validity_status = 'valid' AND recompute_rank = 1 AND metric_value IS NOT NULL
+				) AS x_series
+			JOIN
+				(
+					SELECT assessment_id, metric_value
+					FROM all_metrics
+					WHERE question_id = ? AND metric_key = ? AND validity_status = 'valid' AND recompute_rank = 1 AND metric_value IS NOT NULL
+				) AS y_series ON x_series.assessment_id = y_series.assessment_id
+			JOIN assessment_states a ON x_series.assessment_id = a.id
+			WHERE a.user_id = ? AND a.is_complete = true AND a.invalidated_at IS NULL AND %s;
+		`, getMetricsCTE(), dateRange)
+
+		args := append([]interface{}{userID, x.QuestionID, x.MetricKey, y.QuestionID, y.MetricKey, userID}, dateRangeArgs...)
+		err := database.DB.WithContext(ctx).Raw(query, args...).Scan(&data).Error
+		return data, err
+	}
 
+	query := fmt.Sprintf(`
+		%s,
+		daily AS (
+			SELECT
+				am.question_id,
+				am.metric_key,
+				(a.created_at AT TIME ZONE ?)::date AS day,
+				AVG(am.metric_value) AS metric_value
+			FROM all_metrics am
+			JOIN assessment_states a ON am.assessment_id = a.id
+			WHERE a.user_id = ? AND a.is_complete = true AND a.invalidated_at IS NULL
+				AND am.validity_status = 'valid' AND am.recompute_rank = 1
+				AND am.metric_value IS NOT NULL
+				AND ((am.question_id = ? AND am.metric_key = ?) OR (am.question_id = ? AND am.metric_key = ?))
+				AND %s
+			GROUP BY am.question_id, am.metric_key, day
+		)
+		SELECT
+			x_series.metric_value AS x,
+			y_series.metric_value AS y
+		FROM daily x_series
+		JOIN daily y_series ON y_series.day = x_series.day + ?
+		WHERE x_series.question_id = ? AND x_series.metric_key = ?
+			AND y_series.question_id = ? AND y_series.metric_key = ?
+		ORDER BY x_series.day;
+	`, getMetricsCTE(), dateRange)
+
+	args := append([]interface{}{userID, filter.location(), userID, x.QuestionID, x.MetricKey, y.QuestionID, y.MetricKey}, dateRangeArgs...)
+	args = append(args, lagDays, x.QuestionID, x.MetricKey, y.QuestionID, y.MetricKey)
+	err := database.DB.WithContext(ctx).Raw(query, args...).Scan(&data).Error
 	return data, err
 }
 
-func GetCorrelationData(ctx context.Context, userID int, symptomQuestionID, taskID, metricKey string) ([]CorrelationDataPoint, error) {
-	var data []CorrelationDataPoint
+// GetSeriesValues returns the valid values of several series from a user's
+// completed assessments in one query, for comparing the series pairwise.
+// Assessments marked invalid are left out.
+func GetSeriesValues(ctx context.Context, userID int, keys []SeriesKey) ([]SeriesValue, error) {
+	var values []SeriesValue
+	if len(keys) == 0 {
+		return values, nil
+	}
+	pairs := make([][]interface{}, len(keys))
+	for i, key := range keys {
+		pairs[i] = []interface{}{key.QuestionID, key.MetricKey}
+	}
+
 	query := fmt.Sprintf(`
 		%s
 		SELECT
-			task_metric.metric_value AS metric_value,
-			symptom.metric_value AS symptom_value
-		FROM
-			(
-				SELECT assessment_id, metric_value
-				FROM all_metrics
-				WHERE question_id = ? AND metric_key = ?
-			) AS task_metric
-		JOIN
-			(
-				SELECT assessment_id, metric_value
-				FROM all_metrics
-				WHERE question_id = ? AND metric_key = ?
-			) AS symptom ON task_metric.assessment_id = symptom.assessment_id
-		JOIN assessment_states a ON task_metric.assessment_id = a.id
-		WHERE a.user_id = ? AND a.is_complete = true;
+			am.assessment_id,
+			am.question_id,
+			am.metric_key,
+			am.metric_value AS value
+		FROM all_metrics am
+		JOIN assessment_states a ON am.assessment_id = a.id
+		WHERE a.user_id = ? AND a.is_complete = true AND a.invalidated_at IS NULL
+			AND am.validity_status = 'valid' AND am.recompute_rank = 1
+			AND am.metric_value IS NOT NULL AND (am.question_id, am.metric_key) IN ?;
 	`, getMetricsCTE())
 
-	err := database.DB.WithContext(ctx).Raw(query, taskID, metricKey, symptomQuestionID, symptomQuestionID, userID).Scan(&data).Error
-	return data, err
+	err := database.DB.WithContext(ctx).Raw(query, userID, userID, pairs).Scan(&values).Error
+	return values, err
+}
+
+// FlaggedRun is a cognitive test run that failed one or more validity rules.
+type FlaggedRun struct {
+	Date    time.Time      `json:"date"`
+	Reasons pq.StringArray `json:"reasons"`
+}
+
+// GetFlaggedRuns lists a user's invalid runs of a cognitive test, newest first.
+func GetFlaggedRuns(ctx context.Context, userID int, test cognitive.CognitiveTest) ([]FlaggedRun, error) {
+	var runs []FlaggedRun
+	query := fmt.Sprintf(`
+		SELECT r.created_at AS date, r.validity_reasons AS reasons
+		FROM %s AS r
+		JOIN assessment_states a ON r.assessment_id = a.id
+		WHERE a.user_id = ? AND r.validity_status = ?
+		ORDER BY r.created_at DESC;
+	`, cognitive.LatestResults(test.ResultsTable()))
+
+	err := database.DB.WithContext(ctx).Raw(query, userID, models.ValidityInvalid).Scan(&runs).Error
+	return runs, err
 }
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"crapp-go/internal/database"
+	"crapp-go/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// GrantClinicianAccess lets a clinician review a patient's assessments. Granting
+// access that already exists does nothing.
+func GrantClinicianAccess(ctx context.Context, patientID, clinicianID uint) error {
+	access := &models.ClinicianAccess{PatientID: patientID, ClinicianID: clinicianID}
+	return database.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(access).Error
+}
+
+// RevokeClinicianAccess removes a clinician's access to a patient's assessments.
+// The row is deleted outright so the access can be granted again later.
+func RevokeClinicianAccess(ctx context.Context, patientID, clinicianID uint) error {
+	return database.DB.WithContext(ctx).Unscoped().
+		Where("patient_id = ? AND clinician_id = ?", patientID, clinicianID).
+		Delete(&models.ClinicianAccess{}).Error
+}
+
+// HasClinicianAccess reports whether the patient has granted the clinician access
+// to their assessments.
+func HasClinicianAccess(ctx context.Context, clinicianID, patientID uint) (bool, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).Model(&models.ClinicianAccess{}).
+		Where("patient_id = ? AND clinician_id = ?", patientID, clinicianID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetClinicians returns the users a patient has granted access to, by email.
+func GetClinicians(ctx context.Context, patientID uint) ([]models.User, error) {
+	var users []models.User
+	err := database.DB.WithContext(ctx).
+		Joins("JOIN clinician_accesses ca ON ca.clinician_id = users.id AND ca.deleted_at IS NULL").
+		Where("ca.patient_id = ?", patientID).
+		Order("users.email").
+		Find(&users).Error
+	return users, err
+}
+
+// GetPatients returns the users who have granted a clinician access, by email.
+func GetPatients(ctx context.Context, clinicianID uint) ([]models.User, error) {
+	var users []models.User
+	err := database.DB.WithContext(ctx).
+		Joins("JOIN clinician_accesses ca ON ca.patient_id = users.id AND ca.deleted_at IS NULL").
+		Where("ca.clinician_id = ?", clinicianID).
+		Order("users.email").
+		Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,94 @@
+// server/internal/repository/history.go
+package repository
+
+import (
+	"context"
+	"crapp-go/internal/cognitive"
+	"crapp-go/internal/database"
+	"crapp-go/internal/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AssessmentSummary is one entry of a user's assessment history.
+type AssessmentSummary struct {
+	ID                 int
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	IsComplete         bool
+	InvalidatedAt      *time.Time
+	InvalidationReason string
+	FlaggedTests       int // Cognitive test runs of the assessment that failed a validity rule
+}
+
+// ListAssessments returns one page of a user's assessments, newest first, with
+// the total number of assessments. Pages are numbered from 1.
+func ListAssessments(ctx context.Context, userID, page, pageSize int) ([]AssessmentSummary, int64, error) {
+	var total int64
+	if err := database.DB.WithContext(ctx).Model(&models.AssessmentState{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	flagged := make([]string, 0)
+	for _, test := range cognitive.All() {
+		flagged = append(flagged, fmt.Sprintf(
+			"SELECT assessment_id FROM %s AS r WHERE r.validity_status = '%s'",
+			cognitive.LatestResults(test.ResultsTable()), models.ValidityInvalid,
+		))
+	}
+	flaggedTests := "0"
+	if len(flagged) > 0 {
+		flaggedTests = fmt.Sprintf("(SELECT COUNT(*) FROM (%s) AS flagged WHERE flagged.assessment_id = a.id)", strings.Join(flagged, " UNION ALL "))
+	}
+
+	var summaries []AssessmentSummary
+	query := fmt.Sprintf(`
+		SELECT
+			a.id,
+			a.created_at,
+			a.updated_at,
+			a.is_complete,
+			a.invalidated_at,
+			a.invalidation_reason,
+			%s AS flagged_tests
+		FROM assessment_states a
+		WHERE a.user_id = ?
+		ORDER BY a.created_at DESC
+		LIMIT ? OFFSET ?;
+	`, flaggedTests)
+
+	err := database.DB.WithContext(ctx).Raw(query, userID, pageSize, (page-1)*pageSize).Scan(&summaries).Error
+	return summaries, total, err
+}
+
+// GetAssessmentState loads an assessment by ID, whoever it belongs to.
+func GetAssessmentState(ctx context.Context, assessmentID uint) (*models.AssessmentState, error) {
+	var state models.AssessmentState
+	if err := database.DB.WithContext(ctx).First(&state, assessmentID).Error; err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// InvalidateAssessment marks an assessment invalid, recording who did so and
+// why. The assessment and its data are kept. UpdatedAt is left alone so the
+// assessment's duration is unchanged.
+func InvalidateAssessment(ctx context.Context, assessmentID uint, invalidatedBy int, reason string) error {
+	return database.DB.WithContext(ctx).Model(&models.AssessmentState{}).Where("id = ?", assessmentID).
+		UpdateColumns(map[string]interface{}{
+			"invalidated_at":      time.Now(),
+			"invalidated_by_id":   invalidatedBy,
+			"invalidation_reason": reason,
+		}).Error
+}
+
+// RestoreAssessment clears an assessment's invalidation.
+func RestoreAssessment(ctx context.Context, assessmentID uint) error {
+	return database.DB.WithContext(ctx).Model(&models.AssessmentState{}).Where("id = ?", assessmentID).
+		UpdateColumns(map[string]interface{}{
+			"invalidated_at":      nil,
+			"invalidated_by_id":   nil,
+			"invalidation_reason": "",
+		}).Error
+}
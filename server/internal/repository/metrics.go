@@ -2,10 +2,141 @@
 package repository
 
 import (
+	"context"
 	"crapp-go/internal/database"
 	"crapp-go/internal/models"
+	"slices"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func SaveMetric(metric models.AssessmentMetric) error {
-	return database.DB.Create(&metric).Error
+// SaveInteractionChunks stores interaction chunks, skipping any that were already
+// received. It returns the number of chunks that were new.
+func SaveInteractionChunks(ctx context.Context, chunks []models.InteractionChunk) (int64, error) {
+	if len(chunks) == 0 {
+		return 0, nil
+	}
+	result := database.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&chunks)
+	return result.RowsAffected, result.Error
+}
+
+// QuarantineInteractionChunks stores chunks that could not be attributed, skipping
+// any that were already quarantined.
+func QuarantineInteractionChunks(ctx context.Context, chunks []models.QuarantinedInteractionChunk) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+	return database.DB.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&chunks).Error
+}
+
+// GetPendingInteractionChunks returns the chunks of an assessment that have not yet
+// been folded into its event streams, in the order the client produced them.
+func GetPendingInteractionChunks(ctx context.Context, assessmentID uint) ([]models.InteractionChunk, error) {
+	var chunks []models.InteractionChunk
+	err := conn(ctx).
+		Where("assessment_id = ? AND data IS NOT NULL", assessmentID).
+		Order("session_start, session_id, sequence").
+		Find(&chunks).Error
+	return chunks, err
+}
+
+// GetInteractionDataQuality returns the number of implausible events dropped from
+// all chunks of an assessment, and how many of those were clock jumps.
+func GetInteractionDataQuality(ctx context.Context, assessmentID uint) (dropped, clockJumps int, err error) {
+	var totals struct {
+		Dropped    int
+		ClockJumps int
+	}
+	err = conn(ctx).
+		Model(&models.InteractionChunk{}).
+		Select("COALESCE(SUM(dropped_events), 0) AS dropped, COALESCE(SUM(clock_jumps), 0) AS clock_jumps").
+		Where("assessment_id = ?", assessmentID).
+		Scan(&totals).Error
+	return totals.Dropped, totals.ClockJumps, err
+}
+
+// GetQuestionMetrics returns the current interaction metrics of one question of an
+// assessment, by metric key.
+func GetQuestionMetrics(ctx context.Context, assessmentID uint, questionID string) (map[string]float64, error) {
+	var rows []models.AssessmentMetric
+	err := conn(ctx).
+		Where("assessment_id = ? AND question_id = ?", assessmentID, questionID).
+		Order("id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	// Rows of earlier algorithm versions are kept; the latest one of each key wins.
+	values := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		values[row.MetricKey] = row.MetricValue
+	}
+	return values, nil
+}
+
+// ReplaceInteractionMetrics swaps the interaction metrics of an assessment for a
+// freshly computed set in a single transaction. Only rows of the same metric and
+// algorithm version are replaced; values from earlier versions are kept, as for
+// cognitive results, so they can still be charted by version.
+func ReplaceInteractionMetrics(ctx context.Context, assessmentID uint, metrics []models.AssessmentMetric) error {
+	return conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(metrics) == 0 {
+			return nil
+		}
+		versions := make(map[int][]string)
+		for i := range metrics {
+			metrics[i].AssessmentID = assessmentID
+			version := metrics[i].AlgorithmVersion
+			if !slices.Contains(versions[version], metrics[i].MetricKey) {
+				versions[version] = append(versions[version], metrics[i].MetricKey)
+			}
+		}
+		for version, keys := range versions {
+			err := tx.Where("assessment_id = ? AND algorithm_version = ? AND metric_key IN ?", assessmentID, version, keys).
+				Delete(&models.AssessmentMetric{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Create(&metrics).Error
+	})
+}
+
+// GetInteractionEventStreams returns the stored event streams of an assessment.
+func GetInteractionEventStreams(ctx context.Context, assessmentID uint) ([]models.InteractionEventStream, error) {
+	var streams []models.InteractionEventStream
+	err := conn(ctx).
+		Where("assessment_id = ?", assessmentID).
+		Order("question_id").
+		Find(&streams).Error
+	return streams, err
+}
+
+// SaveInteractionEventStreams writes the event streams of an assessment, replacing
+// the stored stream of each question, and clears the payload of the chunks that
+// were folded into them. The chunk rows themselves are kept so retransmissions
+// are still recognised.
+func SaveInteractionEventStreams(ctx context.Context, streams []models.InteractionEventStream, foldedChunkIDs []uint) error {
+	return conn(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(streams) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "assessment_id"}, {Name: "question_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"updated_at", "origin", "encoding", "movement_count", "interaction_count", "keyboard_event_count", "gesture_count", "page_event_count", "data"}),
+			}).Create(&streams).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(foldedChunkIDs) == 0 {
+			return nil
+		}
+		return tx.Model(&models.InteractionChunk{}).
+			Where("id IN ?", foldedChunkIDs).
+			Update("data", nil).Error
+	})
 }
@@ -0,0 +1,42 @@
+// server/internal/repository/norms.go
+package repository
+
+import (
+	"context"
+	"crapp-go/internal/database"
+	"crapp-go/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ReplaceNormativeEntries imports a normative table. Every metric in the table has
+// its previous entries replaced, so re-importing a corrected file doesn't leave
+// stale groups behind. Metrics not in the table are left alone.
+func ReplaceNormativeEntries(ctx context.Context, entries []models.NormativeEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		replaced := make(map[[2]string]bool)
+		for _, e := range entries {
+			key := [2]string{e.Test, e.MetricKey}
+			if replaced[key] {
+				continue
+			}
+			replaced[key] = true
+			if err := tx.Unscoped().Where("test = ? AND metric_key = ?", e.Test, e.MetricKey).Delete(&models.NormativeEntry{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&entries).Error
+	})
+}
+
+// GetNormativeEntries returns every normative group for a test metric.
+func GetNormativeEntries(ctx context.Context, test, metricKey string) ([]models.NormativeEntry, error) {
+	var entries []models.NormativeEntry
+	err := database.DB.WithContext(ctx).
+		Where("test = ? AND metric_key = ?", test, metricKey).
+		Find(&entries).Error
+	return entries, err
+}
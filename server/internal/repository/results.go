@@ -2,10 +2,15 @@
 package repository
 
 import (
+	"context"
+	"crapp-go/internal/cognitive"
 	"crapp-go/internal/database"
-	"crapp-go/internal/metrics" // Import metrics package
 	"crapp-go/internal/models"
+	"encoding/json"
+	"fmt"
+	"time"
 
+	"github.com/lib/pq"
 	"gorm.io/gorm"
 )
 
@@ -19,67 +24,64 @@ func SaveAnswer(assessmentID uint, questionID string, answerValue string) error
 	return database.DB.Where(models.Answer{AssessmentID: assessmentID, QuestionID: questionID}).Assign(answer).FirstOrCreate(&answer).Error
 }
 
-// SaveCPTResultTx saves the summary and all granular events for a CPT test in a single transaction.
-func SaveCPTResultTx(summary models.CPTResult, events []models.CPTEvent) error {
+// SaveCognitiveSubmission saves a scored cognitive test run in a single transaction.
+func SaveCognitiveSubmission(submission cognitive.Submission) error {
 	return database.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&summary).Error; err != nil {
-			return err
-		}
-		for i := range events {
-			events[i].ResultID = summary.ID
-		}
-		if err := tx.Create(&events).Error; err != nil {
-			return err
-		}
-		return nil
+		return submission.Save(tx)
 	})
 }
 
-// SaveDSTResultTx saves the summary and all attempts for a DST test in a single transaction.
-func SaveDSTResultTx(summary models.DSTResult, attempts []metrics.DigitSpanAttempt) error {
-	return database.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&summary).Error; err != nil {
-			return err
-		}
-		dstAttempts := make([]models.DSTAttempt, len(attempts))
-		for i, attempt := range attempts {
-			dstAttempts[i] = models.DSTAttempt{
-				ResultID:  summary.ID,
-				Span:      attempt.Span,
-				Trial:     attempt.Trial,
-				Sequence:  attempt.Sequence,
-				Input:     attempt.Input,
-				IsCorrect: attempt.Correct,
-				Timestamp: attempt.Timestamp,
-			}
-		}
-		if err := tx.Create(&dstAttempts).Error; err != nil {
-			return err
-		}
-		return nil
-	})
+// InvalidateCognitiveResult marks the latest result of a test in an assessment as
+// invalid for the given reasons. Reasons the result already carries are not added
+// again, so it is safe to call repeatedly.
+func InvalidateCognitiveResult(ctx context.Context, test cognitive.CognitiveTest, assessmentID uint, reasons []string) error {
+	query := fmt.Sprintf(`
+		UPDATE %[1]s SET
+			validity_status = ?,
+			validity_reasons = COALESCE(validity_reasons, '{}') || ARRAY(
+				SELECT unnest(?::text[]) EXCEPT SELECT unnest(COALESCE(validity_reasons, '{}'))
+			)
+		WHERE id = (
+			SELECT id FROM %[1]s
+			WHERE assessment_id = ? AND deleted_at IS NULL
+			ORDER BY id DESC LIMIT 1
+		);
+	`, test.ResultsTable())
+	return conn(ctx).Exec(query, models.ValidityInvalid, pq.StringArray(reasons), assessmentID).Error
 }
 
-// SaveTMTResultTx saves the summary and all clicks for a TMT test in a single transaction.
-func SaveTMTResultTx(summary models.TMTResult, clicks []metrics.Click) error {
-	return database.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Create(&summary).Error; err != nil {
-			return err
-		}
-		tmtClicks := make([]models.TMTClick, len(clicks))
-		for i, click := range clicks {
-			tmtClicks[i] = models.TMTClick{
-				ResultID:    summary.ID,
-				X:           click.X,
-				Y:           click.Y,
-				Time:        click.Time,
-				TargetItem:  click.TargetItem,
-				CurrentPart: click.CurrentPart,
-			}
-		}
-		if err := tx.Create(&tmtClicks).Error; err != nil {
-			return err
-		}
-		return nil
-	})
+// CountCognitiveRuns returns how many times a user has completed the given cognitive test.
+func CountCognitiveRuns(ctx context.Context, userID uint, test cognitive.CognitiveTest) (int64, error) {
+	var count int64
+	err := database.DB.WithContext(ctx).
+		Table(test.ResultsTable()+" AS r").
+		Joins("JOIN assessment_states a ON r.assessment_id = a.id").
+		Where("a.user_id = ? AND r.deleted_at IS NULL", userID).
+		Distinct("r.assessment_id").
+		Count(&count).Error
+	return count, err
+}
+
+// RawResult is the stored client payload of a cognitive test run.
+type RawResult struct {
+	ID               uint
+	AssessmentID     uint
+	CreatedAt        time.Time
+	AlgorithmVersion int
+	RawData          json.RawMessage
+}
+
+// GetLatestRawResults returns the raw payload of the latest result for every
+// assessment that has one, oldest first.
+func GetLatestRawResults(ctx context.Context, test cognitive.CognitiveTest) ([]RawResult, error) {
+	var results []RawResult
+	query := fmt.Sprintf(`
+		SELECT r.id, r.assessment_id, r.created_at, r.algorithm_version, r.raw_data
+		FROM %s AS r
+		WHERE r.raw_data IS NOT NULL
+		ORDER BY r.created_at;
+	`, cognitive.LatestResults(test.ResultsTable()))
+
+	err := database.DB.WithContext(ctx).Raw(query).Scan(&results).Error
+	return results, err
 }
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"crapp-go/internal/database"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// userLockClass is the first key of the advisory locks taken on users. Locks
+// taken with two keys never collide with the single-key assessment locks.
+const userLockClass = 1
+
+// WithAssessmentLock runs fn in a transaction that holds an advisory lock on the
+// assessment, so callers working on the same assessment run one at a time.
+// Repository functions called with the context passed to fn take part in the
+// transaction, and everything they write is committed or rolled back together.
+func WithAssessmentLock(ctx context.Context, assessmentID uint, fn func(ctx context.Context) error) error {
+	return withAdvisoryLock(ctx, "SELECT pg_advisory_xact_lock(?)", []interface{}{int64(assessmentID)}, fn)
+}
+
+// WithUserLock is like WithAssessmentLock, but holds the lock on a user, so work
+// on the user's baselines and change events runs one call at a time.
+func WithUserLock(ctx context.Context, userID int, fn func(ctx context.Context) error) error {
+	return withAdvisoryLock(ctx, "SELECT pg_advisory_xact_lock(?, ?)", []interface{}{userLockClass, userID}, fn)
+}
+
+// withAdvisoryLock runs fn in a transaction after taking an advisory lock with
+// the lock statement and its keys.
+func withAdvisoryLock(ctx context.Context, lock string, keys []interface{}, fn func(ctx context.Context) error) error {
+	return database.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(lock, keys...).Error; err != nil {
+			return err
+		}
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// conn returns the transaction started by WithAssessmentLock or WithUserLock, if
+// ctx carries one, or the shared connection pool.
+func conn(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return database.DB.WithContext(ctx)
+}
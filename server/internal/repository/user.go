@@ -36,8 +36,14 @@ func GetUserByID(ctx context.Context, id uint) (*models.User, error) {
 	return &user, result.Error
 }
 
-func UpdateUser(ctx context.Context, userID uint, firstName, lastName string) error {
-	return database.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{"first_name": firstName, "last_name": lastName}).Error
+func UpdateUser(ctx context.Context, userID uint, firstName, lastName string, demographics models.Demographics) error {
+	return database.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"first_name":      firstName,
+		"last_name":       lastName,
+		"date_of_birth":   demographics.DateOfBirth,
+		"education_years": demographics.EducationYears,
+		"sex":             demographics.Sex,
+	}).Error
 }
 
 func UpdateUserPassword(ctx context.Context, userID uint, newPassword string) error {
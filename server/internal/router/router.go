@@ -80,9 +80,10 @@ func Setup(log *zap.Logger, assessment *models.Assessment) *gin.Engine {
 	// Handlers and routes
 	authHandler := handlers.NewAuthHandler(log, assessment)
 	assessmentHandler := handlers.NewAssessmentHandler(log, assessment)
-	metricsHandler := handlers.NewMetricsHandler(log)
+	metricsHandler := handlers.NewMetricsHandler(log, assessment)
 	resultsHandler := handlers.NewResultsHandler(log, assessment)
 	userHandler := handlers.NewUserHandler(log)
+	historyHandler := handlers.NewHistoryHandler(log, assessment)
 
 	rateLimitStore := ratelimit.InMemoryStore(&ratelimit.InMemoryOptions{
 		Rate:  time.Minute,
@@ -137,6 +138,13 @@ func Setup(log *zap.Logger, assessment *models.Assessment) *gin.Engine {
 			assessmentRoutes.POST("/prev", assessmentHandler.PreviousQuestion)
 			assessmentRoutes.POST("/next", assessmentHandler.NextQuestion)
 			assessmentRoutes.GET("/results", resultsHandler.ShowResults)
+			assessmentRoutes.POST("/results/baseline", resultsHandler.SaveBaseline)
+			assessmentRoutes.GET("/results/matrix", resultsHandler.ShowCorrelationMatrix)
+			assessmentRoutes.GET("/results/calendar", resultsHandler.ShowCalendar)
+			assessmentRoutes.GET("/history", historyHandler.ListAssessments)
+			assessmentRoutes.GET("/history/:id", historyHandler.ShowAssessment)
+			assessmentRoutes.POST("/history/:id/invalidate", historyHandler.InvalidateAssessment)
+			assessmentRoutes.POST("/history/:id/restore", historyHandler.RestoreAssessment)
 		}
 
 		profileRoutes := authorized.Group("/profile")
@@ -150,6 +158,9 @@ func Setup(log *zap.Logger, assessment *models.Assessment) *gin.Engine {
 			profileRoutes.POST("/update-password", userHandler.UpdatePassword)
 			profileRoutes.POST("/update-notifications", userHandler.UpdateNotificationSettings)
 			profileRoutes.POST("/delete", userHandler.DeleteAccount)
+			profileRoutes.GET("/clinicians", userHandler.ShowClinicianAccess)
+			profileRoutes.POST("/clinicians", userHandler.AddClinician)
+			profileRoutes.POST("/clinicians/:id/remove", userHandler.RemoveClinician)
 		}
 	}
 
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"crapp-go/internal/config"
+	"crapp-go/internal/models"
+	"crapp-go/internal/repository"
+)
+
+// ResolveBaseline returns the user's stored baseline for a metric. It is always
+// computed from the latest recomputation of every run, so the change events
+// measured against it do not depend on what anyone last viewed. The stored
+// baseline is reused while it is full; otherwise it is recomputed and stored,
+// and the metric's change events are refreshed. A non-nil window replaces the
+// stored window. A nil window keeps the stored window, or covers the first
+// configured number of assessments when the user has no baseline yet.
+//
+// ResolveBaseline writes to the database under the user's lock. It is only called
+// when the user changes the window; pages showing a baseline use ViewBaseline.
+func ResolveBaseline(ctx context.Context, userID int, question models.Question, metricKey string, window *models.Baseline) (*models.Baseline, error) {
+	var baseline *models.Baseline
+	err := repository.WithUserLock(ctx, userID, func(ctx context.Context) error {
+		var recomputed bool
+		var err error
+		baseline, recomputed, err = resolveBaseline(ctx, userID, question.ID, metricKey, window)
+		if err != nil || !recomputed {
+			return err
+		}
+		return refreshChangeEvents(ctx, userID, question, metricKey, baseline)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// resolveBaseline implements ResolveBaseline and reports whether the baseline was
+// recomputed.
+func resolveBaseline(ctx context.Context, userID int, questionID, metricKey string, window *models.Baseline) (*models.Baseline, bool, error) {
+	stored, err := repository.GetBaseline(ctx, userID, questionID, metricKey)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if window == nil {
+		window = storedWindow(stored)
+	}
+	window.AlgorithmVersion = 0
+	if stored != nil && stored.SameWindow(window) && stored.IsFilled() {
+		return stored, false, nil
+	}
+
+	baseline := newBaseline(userID, questionID, metricKey, window)
+	if err := repository.ComputeBaselineStats(ctx, baseline); err != nil {
+		return nil, false, err
+	}
+	if err := repository.SaveBaseline(ctx, baseline); err != nil {
+		return nil, false, err
+	}
+	return baseline, true, nil
+}
+
+// ViewBaseline returns the user's baseline for a metric over the stored window,
+// from values computed by the given algorithm version (0 for the latest
+// recomputation). Nothing is stored: the stored baseline is returned as it is
+// when it is current, and any other baseline is computed for this view only.
+func ViewBaseline(ctx context.Context, userID int, questionID, metricKey string, algorithmVersion int) (*models.Baseline, error) {
+	stored, err := repository.GetBaseline(ctx, userID, questionID, metricKey)
+	if err != nil {
+		return nil, err
+	}
+	if algorithmVersion == 0 && stored != nil && stored.AlgorithmVersion == 0 && stored.IsFilled() {
+		return stored, nil
+	}
+
+	window := storedWindow(stored)
+	window.AlgorithmVersion = algorithmVersion
+	baseline := newBaseline(userID, questionID, metricKey, window)
+	baseline.UpdatedAt = time.Now()
+	if err := repository.ComputeBaselineStats(ctx, baseline); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// storedWindow returns the window of a stored baseline, or the default window
+// when there is none.
+func storedWindow(stored *models.Baseline) *models.Baseline {
+	if stored == nil {
+		return &models.Baseline{Method: models.BaselineFirstN, FirstN: config.Conf.Results.BaselineAssessments}
+	}
+	return &models.Baseline{Method: stored.Method, FirstN: stored.FirstN, StartDate: stored.StartDate, EndDate: stored.EndDate}
+}
+
+func newBaseline(userID int, questionID, metricKey string, window *models.Baseline) *models.Baseline {
+	return &models.Baseline{
+		UserID:           userID,
+		QuestionID:       questionID,
+		MetricKey:        metricKey,
+		Method:           window.Method,
+		FirstN:           window.FirstN,
+		StartDate:        window.StartDate,
+		EndDate:          window.EndDate,
+		AlgorithmVersion: window.AlgorithmVersion,
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+
+	"crapp-go/internal/repository"
+	"crapp-go/internal/stats"
+)
+
+// CorrelationMatrix returns the Pearson correlation of every pair of series over
+// the assessments that recorded both. A cell is nil when the pair cannot be
+// correlated, e.g. too few shared assessments or a series that never changes.
+func CorrelationMatrix(ctx context.Context, userID int, keys []repository.SeriesKey) ([][]*stats.Correlation, error) {
+	values, err := repository.GetSeriesValues(ctx, userID, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[repository.SeriesKey]int, len(keys))
+	for i, key := range keys {
+		index[key] = i
+	}
+	byAssessment := make(map[uint]map[int]float64)
+	for _, v := range values {
+		i, ok := index[repository.SeriesKey{QuestionID: v.QuestionID, MetricKey: v.MetricKey}]
+		if !ok {
+			continue
+		}
+		if byAssessment[v.AssessmentID] == nil {
+			byAssessment[v.AssessmentID] = make(map[int]float64)
+		}
+		byAssessment[v.AssessmentID][i] = v.Value
+	}
+
+	matrix := make([][]*stats.Correlation, len(keys))
+	for i := range matrix {
+		matrix[i] = make([]*stats.Correlation, len(keys))
+	}
+	for i := range keys {
+		for j := i; j < len(keys); j++ {
+			var x, y []float64
+			for _, row := range byAssessment {
+				xv, okX := row[i]
+				yv, okY := row[j]
+				if okX && okY {
+					x = append(x, xv)
+					y = append(y, yv)
+				}
+			}
+			if r, err := stats.Pearson(x, y); err == nil {
+				matrix[i][j], matrix[j][i] = &r, &r
+			}
+		}
+	}
+	return matrix, nil
+}
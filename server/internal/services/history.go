@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+
+	"crapp-go/internal/models"
+	"crapp-go/internal/repository"
+)
+
+// InvalidateAssessment marks an assessment invalid on behalf of invalidatedBy,
+// the user who took it or a clinician. Its values leave the user's baselines,
+// so every stored baseline is recomputed with its change events.
+func InvalidateAssessment(ctx context.Context, state *models.AssessmentState, invalidatedBy int, reason string, assessment *models.Assessment) error {
+	if err := repository.InvalidateAssessment(ctx, uint(state.ID), invalidatedBy, reason); err != nil {
+		return err
+	}
+	return refreshBaselines(ctx, state.UserID, assessment)
+}
+
+// RestoreAssessment clears an assessment's invalidation and recomputes the
+// user's baselines with its values back in.
+func RestoreAssessment(ctx context.Context, state *models.AssessmentState, assessment *models.Assessment) error {
+	if err := repository.RestoreAssessment(ctx, uint(state.ID)); err != nil {
+		return err
+	}
+	return refreshBaselines(ctx, state.UserID, assessment)
+}
+
+// refreshBaselines recomputes each of the user's stored baselines over its
+// current window and refreshes the change events measured against it, holding
+// the user's lock.
+func refreshBaselines(ctx context.Context, userID int, assessment *models.Assessment) error {
+	questions := make(map[string]models.Question, len(assessment.Questions))
+	for _, q := range assessment.Questions {
+		questions[q.ID] = q
+	}
+
+	return repository.WithUserLock(ctx, userID, func(ctx context.Context) error {
+		baselines, err := repository.GetBaselines(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for i := range baselines {
+			stored := &baselines[i]
+			baseline := newBaseline(userID, stored.QuestionID, stored.MetricKey, storedWindow(stored))
+			if err := repository.ComputeBaselineStats(ctx, baseline); err != nil {
+				return err
+			}
+			if err := repository.SaveBaseline(ctx, baseline); err != nil {
+				return err
+			}
+			question, ok := questions[baseline.QuestionID]
+			if !ok {
+				continue
+			}
+			if err := refreshChangeEvents(ctx, userID, question, baseline.MetricKey, baseline); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
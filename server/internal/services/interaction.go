@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+
+	"crapp-go/internal/cognitive"
+	"crapp-go/internal/eventstream"
+	"crapp-go/internal/metrics"
+	"crapp-go/internal/models"
+	"crapp-go/internal/repository"
+	"crapp-go/internal/validity"
+)
+
+// FinalizeInteractionMetrics folds the pending chunks of an assessment into its
+// compressed event streams, then recomputes the interaction metrics from the full
+// event history and replaces any previously computed set. It is safe to call
+// again when late chunks arrive after completion.
+//
+// Interruptions recorded while a cognitive test question was on screen are checked
+// against the validity rules, and the test's result is marked invalid if they fail.
+//
+// Completing the assessment and receiving late chunks can both finalize it at the
+// same time, so each call holds a lock on the assessment and does its work in one
+// transaction. Otherwise one call could write streams missing chunks that another
+// had already folded in and cleared.
+func FinalizeInteractionMetrics(ctx context.Context, assessment *models.Assessment, assessmentID uint) error {
+	return repository.WithAssessmentLock(ctx, assessmentID, func(ctx context.Context) error {
+		return finalizeInteractionMetrics(ctx, assessment, assessmentID)
+	})
+}
+
+func finalizeInteractionMetrics(ctx context.Context, assessment *models.Assessment, assessmentID uint) error {
+	chunks, err := repository.GetPendingInteractionChunks(ctx, assessmentID)
+	if err != nil {
+		return err
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	stored, err := repository.GetInteractionEventStreams(ctx, assessmentID)
+	if err != nil {
+		return err
+	}
+	data, streams, err := foldInteractionChunks(assessmentID, stored, chunks)
+	if err != nil {
+		return err
+	}
+	folded := make([]uint, len(chunks))
+	for i, chunk := range chunks {
+		folded[i] = chunk.ID
+	}
+	if err := repository.SaveInteractionEventStreams(ctx, streams, folded); err != nil {
+		return err
+	}
+
+	choiceQuestions := make(map[string]bool)
+	for _, q := range assessment.Questions {
+		if q.IsChoice() {
+			choiceQuestions[q.ID] = true
+		}
+	}
+	calculated := metrics.CalculateInteractionMetrics(data, choiceQuestions)
+	all := append(calculated.GlobalMetrics, calculated.QuestionMetrics...)
+
+	// Events dropped as implausible when the chunks arrived are recorded alongside
+	// the metrics, so a session computed from patchy data can be recognised.
+	dropped, clockJumps, err := repository.GetInteractionDataQuality(ctx, assessmentID)
+	if err != nil {
+		return err
+	}
+	for key, value := range map[string]int{"dropped_events": dropped, "clock_jumps": clockJumps} {
+		all = append(all, models.AssessmentMetric{
+			QuestionID:       "global",
+			MetricKey:        key,
+			MetricValue:      float64(value),
+			AlgorithmVersion: metrics.InteractionMetricVersion(key),
+		})
+	}
+	if err := repository.ReplaceInteractionMetrics(ctx, assessmentID, all); err != nil {
+		return err
+	}
+	return applyInterruptionValidity(ctx, assessment, assessmentID, calculated.QuestionMetrics)
+}
+
+// LoadInteractionData decodes the stored event streams of an assessment back into
+// a single InteractionData for reanalysis. Timestamps are relative to the returned
+// origin, in epoch milliseconds. The data is nil if nothing has been stored.
+func LoadInteractionData(ctx context.Context, assessmentID uint) (*models.InteractionData, float64, error) {
+	streams, err := repository.GetInteractionEventStreams(ctx, assessmentID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decodeEventStreams(streams)
+}
+
+// decodeEventStreams combines the events of an assessment's streams in time
+// order. The data is nil if there are no streams.
+func decodeEventStreams(streams []models.InteractionEventStream) (*models.InteractionData, float64, error) {
+	if len(streams) == 0 {
+		return nil, 0, nil
+	}
+
+	data := &models.InteractionData{}
+	for _, stream := range streams {
+		decoded, err := eventstream.Decode(stream.Data, stream.QuestionID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("event stream %d: %w", stream.ID, err)
+		}
+		data.MouseMovements = append(data.MouseMovements, decoded.MouseMovements...)
+		data.MouseInteractions = append(data.MouseInteractions, decoded.MouseInteractions...)
+		data.KeyboardEvents = append(data.KeyboardEvents, decoded.KeyboardEvents...)
+		data.TouchGestures = append(data.TouchGestures, decoded.TouchGestures...)
+		data.PageEvents = append(data.PageEvents, decoded.PageEvents...)
+	}
+	sortEvents(data)
+	return data, streams[0].Origin, nil
+}
+
+// foldInteractionChunks merges pending chunks into the events of the stored
+// streams and encodes the result as the assessment's new streams. Without stored
+// streams, the clock starts at the first chunk's session.
+func foldInteractionChunks(assessmentID uint, stored []models.InteractionEventStream, chunks []models.InteractionChunk) (*models.InteractionData, []models.InteractionEventStream, error) {
+	data, origin, err := decodeEventStreams(stored)
+	if err != nil {
+		return nil, nil, err
+	}
+	if data == nil {
+		data = &models.InteractionData{}
+		origin = chunks[0].SessionStart
+	}
+
+	if err := mergeInteractionChunks(data, origin, chunks); err != nil {
+		return nil, nil, err
+	}
+	streams, err := encodeEventStreams(assessmentID, origin, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, streams, nil
+}
+
+// mergeInteractionChunks appends chunk events to data. Chunk timestamps are
+// relative to the start of their tracker session, so chunks from other sessions
+// (e.g. after a page reload) are shifted onto the clock that starts at origin.
+func mergeInteractionChunks(data *models.InteractionData, origin float64, chunks []models.InteractionChunk) error {
+	for _, chunk := range chunks {
+		var chunkData models.InteractionData
+		if err := json.Unmarshal(chunk.Data, &chunkData); err != nil {
+			return fmt.Errorf("interaction chunk %d: %w", chunk.ID, err)
+		}
+
+		offset := chunk.SessionStart - origin
+		for _, m := range chunkData.MouseMovements {
+			m.Timestamp += offset
+			data.MouseMovements = append(data.MouseMovements, m)
+		}
+		for _, i := range chunkData.MouseInteractions {
+			i.Timestamp += offset
+			data.MouseInteractions = append(data.MouseInteractions, i)
+		}
+		for _, k := range chunkData.KeyboardEvents {
+			k.Timestamp += offset
+			data.KeyboardEvents = append(data.KeyboardEvents, k)
+		}
+		for _, g := range chunkData.TouchGestures {
+			g.Timestamp += offset
+			data.TouchGestures = append(data.TouchGestures, g)
+		}
+		for _, e := range chunkData.PageEvents {
+			e.Timestamp += offset
+			data.PageEvents = append(data.PageEvents, e)
+		}
+	}
+	sortEvents(data)
+	return nil
+}
+
+// sortEvents puts each event stream in time order. Chunks and stored streams are
+// per question, so events from interleaved questions need reordering.
+func sortEvents(data *models.InteractionData) {
+	sort.SliceStable(data.MouseMovements, func(a, b int) bool {
+		return data.MouseMovements[a].Timestamp < data.MouseMovements[b].Timestamp
+	})
+	sort.SliceStable(data.MouseInteractions, func(a, b int) bool {
+		return data.MouseInteractions[a].Timestamp < data.MouseInteractions[b].Timestamp
+	})
+	sort.SliceStable(data.KeyboardEvents, func(a, b int) bool {
+		return data.KeyboardEvents[a].Timestamp < data.KeyboardEvents[b].Timestamp
+	})
+	sort.SliceStable(data.TouchGestures, func(a, b int) bool {
+		return data.TouchGestures[a].Timestamp < data.TouchGestures[b].Timestamp
+	})
+	sort.SliceStable(data.PageEvents, func(a, b int) bool {
+		return data.PageEvents[a].Timestamp < data.PageEvents[b].Timestamp
+	})
+}
+
+// encodeEventStreams splits data by question and encodes one stream per question.
+func encodeEventStreams(assessmentID uint, origin float64, data *models.InteractionData) ([]models.InteractionEventStream, error) {
+	byQuestion := make(map[string]*models.InteractionData)
+	bucket := func(questionID string) *models.InteractionData {
+		if _, ok := byQuestion[questionID]; !ok {
+			byQuestion[questionID] = &models.InteractionData{}
+		}
+		return byQuestion[questionID]
+	}
+	for _, m := range data.MouseMovements {
+		b := bucket(m.QuestionID)
+		b.MouseMovements = append(b.MouseMovements, m)
+	}
+	for _, i := range data.MouseInteractions {
+		b := bucket(i.QuestionID)
+		b.MouseInteractions = append(b.MouseInteractions, i)
+	}
+	for _, k := range data.KeyboardEvents {
+		b := bucket(k.QuestionID)
+		b.KeyboardEvents = append(b.KeyboardEvents, k)
+	}
+	for _, g := range data.TouchGestures {
+		b := bucket(g.QuestionID)
+		b.TouchGestures = append(b.TouchGestures, g)
+	}
+	for _, e := range data.PageEvents {
+		b := bucket(e.QuestionID)
+		b.PageEvents = append(b.PageEvents, e)
+	}
+
+	streams := make([]models.InteractionEventStream, 0, len(byQuestion))
+	for questionID, questionData := range byQuestion {
+		encoded, err := eventstream.Encode(questionData)
+		if err != nil {
+			return nil, err
+		}
+		streams = append(streams, models.InteractionEventStream{
+			AssessmentID:       assessmentID,
+			QuestionID:         questionID,
+			Origin:             origin,
+			Encoding:           eventstream.Version,
+			MovementCount:      len(questionData.MouseMovements),
+			InteractionCount:   len(questionData.MouseInteractions),
+			KeyboardEventCount: len(questionData.KeyboardEvents),
+			GestureCount:       len(questionData.TouchGestures),
+			PageEventCount:     len(questionData.PageEvents),
+			Data:               encoded,
+		})
+	}
+	return streams, nil
+}
+
+// interruptionFacts are the interaction metrics that validity rules check for
+// cognitive test questions.
+var interruptionFacts = []string{"interruption_count", "time_away"}
+
+// mergeInterruptionFacts adds the interruption metrics recorded for a test's
+// question to the facts of its run. Where the test reported the same fact from
+// its own focus tracking, the larger value is kept.
+func mergeInterruptionFacts(facts, recorded map[string]float64) {
+	for _, key := range interruptionFacts {
+		if value, ok := recorded[key]; ok {
+			facts[key] = max(facts[key], value)
+		}
+	}
+}
+
+// applyInterruptionValidity evaluates the validity rules for each cognitive test
+// question against the interruptions recorded while it was on screen, and marks
+// the test's result invalid when a rule fails. A result is never made valid again
+// here, since the test may have been invalid for other reasons.
+func applyInterruptionValidity(ctx context.Context, assessment *models.Assessment, assessmentID uint, questionMetrics []models.AssessmentMetric) error {
+	facts := make(map[string]map[string]float64)
+	for _, m := range questionMetrics {
+		if !slices.Contains(interruptionFacts, m.MetricKey) {
+			continue
+		}
+		if facts[m.QuestionID] == nil {
+			facts[m.QuestionID] = make(map[string]float64)
+		}
+		facts[m.QuestionID][m.MetricKey] = m.MetricValue
+	}
+
+	for _, question := range assessment.Questions {
+		test, ok := cognitive.Get(question.Type)
+		if !ok || facts[question.ID] == nil {
+			continue
+		}
+		result := validity.Evaluate(test.Type(), facts[question.ID])
+		if result.ValidityStatus != models.ValidityInvalid {
+			continue
+		}
+		if err := repository.InvalidateCognitiveResult(ctx, test, assessmentID, result.ValidityReasons); err != nil {
+			return fmt.Errorf("failed to invalidate %s result: %w", test.Type(), err)
+		}
+	}
+	return nil
+}
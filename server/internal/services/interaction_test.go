@@ -0,0 +1,152 @@
+package services
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"crapp-go/internal/models"
+)
+
+// Two tracker sessions of one assessment: the page was reloaded five seconds in.
+const (
+	firstSession  = 1_000_000.0
+	secondSession = 1_005_000.0
+)
+
+func chunk(id uint, sessionID string, sequence int, sessionStart float64, data string) models.InteractionChunk {
+	c := models.InteractionChunk{SessionID: sessionID, Sequence: sequence, SessionStart: sessionStart, Data: []byte(data)}
+	c.ID = id
+	return c
+}
+
+// pendingChunks are in the order GetPendingInteractionChunks returns them. The
+// second session's chunk covers q2 and falls between the first session's two.
+func pendingChunks() []models.InteractionChunk {
+	return []models.InteractionChunk{
+		chunk(1, "a", 0, firstSession, `{"movements":[{"x":1,"y":1,"timestamp":100,"questionId":"q1"},{"x":2,"y":2,"timestamp":200,"questionId":"q1"}],
+			"keyboardEvents":[{"type":"keydown","timestamp":150,"questionId":"q1"}]}`),
+		chunk(2, "a", 1, firstSession, `{"movements":[{"x":3,"y":3,"timestamp":5100,"questionId":"q1"}]}`),
+		chunk(3, "b", 0, secondSession, `{"movements":[{"x":4,"y":4,"timestamp":50,"questionId":"q2"}]}`),
+	}
+}
+
+// byQuestion indexes streams by question, as they are stored.
+func byQuestion(streams []models.InteractionEventStream) map[string]models.InteractionEventStream {
+	indexed := make(map[string]models.InteractionEventStream, len(streams))
+	for _, s := range streams {
+		indexed[s.QuestionID] = s
+	}
+	return indexed
+}
+
+func TestFoldInteractionChunks(t *testing.T) {
+	data, streams, err := foldInteractionChunks(7, nil, pendingChunks())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var timestamps []float64
+	var questions []string
+	for _, m := range data.MouseMovements {
+		timestamps = append(timestamps, m.Timestamp)
+		questions = append(questions, m.QuestionID)
+	}
+	// The second session's event is shifted onto the first session's clock and
+	// sorted among its events.
+	if want := []float64{100, 200, 5050, 5100}; !slices.Equal(timestamps, want) {
+		t.Errorf("movement timestamps = %v, want %v", timestamps, want)
+	}
+	if want := []string{"q1", "q1", "q2", "q1"}; !slices.Equal(questions, want) {
+		t.Errorf("movement questions = %v, want %v", questions, want)
+	}
+
+	if len(streams) != 2 {
+		t.Fatalf("got %d streams, want one per question", len(streams))
+	}
+	indexed := byQuestion(streams)
+	for _, tt := range []struct {
+		questionID      string
+		movements, keys int
+	}{
+		{"q1", 3, 1},
+		{"q2", 1, 0},
+	} {
+		s := indexed[tt.questionID]
+		if s.AssessmentID != 7 || s.Origin != firstSession || s.MovementCount != tt.movements || s.KeyboardEventCount != tt.keys {
+			t.Errorf("%s stream = assessment %d, origin %v, %d movements, %d keyboard events; want 7, %v, %d, %d",
+				tt.questionID, s.AssessmentID, s.Origin, s.MovementCount, s.KeyboardEventCount, firstSession, tt.movements, tt.keys)
+		}
+	}
+}
+
+func TestFoldLateChunks(t *testing.T) {
+	// Chunks arriving after completion are folded onto the stored streams.
+	// Folded chunks are no longer pending, so the result must match folding
+	// everything at once, without repeating the events already stored.
+	chunks := pendingChunks()
+	_, stored, err := foldInteractionChunks(7, nil, chunks[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	late, lateStreams, err := foldInteractionChunks(7, stored, chunks[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, allStreams, err := foldInteractionChunks(7, nil, chunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(late, all) {
+		t.Errorf("folding late chunks =\n%+v\nwant\n%+v", late, all)
+	}
+	got, want := byQuestion(lateStreams), byQuestion(allStreams)
+	for questionID, w := range want {
+		g := got[questionID]
+		if g.Origin != w.Origin || g.MovementCount != w.MovementCount || g.KeyboardEventCount != w.KeyboardEventCount {
+			t.Errorf("%s stream after late chunks = origin %v, %d movements, %d keyboard events; want %v, %d, %d",
+				questionID, g.Origin, g.MovementCount, g.KeyboardEventCount, w.Origin, w.MovementCount, w.KeyboardEventCount)
+		}
+	}
+}
+
+func TestFoldLateChunkFromEarlierSession(t *testing.T) {
+	// The origin is fixed once streams are stored, so a late chunk from a session
+	// that started earlier lands before it.
+	chunks := pendingChunks()
+	_, stored, err := foldInteractionChunks(7, nil, chunks[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, streams, err := foldInteractionChunks(7, stored, chunks[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := data.MouseMovements[0].Timestamp; first != -4900 {
+		t.Errorf("first movement at %v, want -4900", first)
+	}
+	for _, s := range streams {
+		if s.Origin != secondSession {
+			t.Errorf("%s stream origin = %v, want %v", s.QuestionID, s.Origin, secondSession)
+		}
+	}
+}
+
+func TestFoldRejectsMalformedChunk(t *testing.T) {
+	chunks := append(pendingChunks(), chunk(9, "b", 1, secondSession, `{"movements":`))
+	_, _, err := foldInteractionChunks(7, nil, chunks)
+	if err == nil || !strings.Contains(err.Error(), "interaction chunk 9") {
+		t.Errorf("err = %v, want one naming chunk 9", err)
+	}
+}
+
+func TestMergeInterruptionFacts(t *testing.T) {
+	facts := map[string]float64{"interruption_count": 2, "response_count": 10}
+	mergeInterruptionFacts(facts, map[string]float64{"interruption_count": 1, "time_away": 3000, "path_efficiency": 0.9})
+	want := map[string]float64{"interruption_count": 2, "time_away": 3000, "response_count": 10}
+	if !reflect.DeepEqual(facts, want) {
+		t.Errorf("facts = %v, want %v", facts, want)
+	}
+}
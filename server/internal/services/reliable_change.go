@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"math"
+
+	"crapp-go/internal/cognitive"
+	"crapp-go/internal/config"
+	"crapp-go/internal/models"
+	"crapp-go/internal/repository"
+)
+
+// refreshChangeEvents recomputes a user's change events for a cognitive test
+// metric against their stored baseline. Both the baseline and the values use the
+// latest recomputation of every run, so other parts of the system always read
+// the same events. Only metrics with a configured test-retest reliability are
+// checked, and values inside the baseline window are never compared with it.
+func refreshChangeEvents(ctx context.Context, userID int, question models.Question, metricKey string, baseline *models.Baseline) error {
+	test, ok := cognitive.Get(question.Type)
+	if !ok {
+		return nil
+	}
+	settings := config.Conf.Results.ReliableChange
+	rule, ok := settings.Lookup(test.Type(), metricKey)
+	if !ok {
+		return nil
+	}
+
+	points, err := repository.GetTimelineData(ctx, userID, []repository.SeriesKey{{QuestionID: question.ID, MetricKey: metricKey}}, repository.SeriesFilter{
+		ValidityStatus: models.ValidityValid,
+	})
+	if err != nil {
+		return err
+	}
+
+	events := make([]models.ChangeEvent, 0)
+	for i, point := range points {
+		if baseline.Covers(i, point.Date) {
+			continue
+		}
+		rci, ok := reliableChangeIndex(baseline, rule.Reliability, point.Value)
+		if !ok || math.Abs(rci) <= settings.Threshold {
+			continue
+		}
+		direction := models.ChangeImprovement
+		if (rci < 0) != rule.LowerIsBetter {
+			direction = models.ChangeDecline
+		}
+		events = append(events, models.ChangeEvent{
+			UserID:       userID,
+			AssessmentID: point.AssessmentID,
+			QuestionID:   question.ID,
+			MetricKey:    metricKey,
+			Date:         point.Date,
+			Value:        point.Value,
+			BaselineMean: baseline.Mean,
+			RCI:          rci,
+			Direction:    direction,
+		})
+	}
+	return repository.ReplaceChangeEvents(ctx, userID, question.ID, metricKey, events)
+}
+
+// RecordReliableChanges refreshes the change events of every cognitive test metric
+// with a configured reliability. It is run when an assessment is completed, as a
+// new score may change both the baseline and the events. It holds the user's lock,
+// like every other caller that rebuilds the user's change events.
+func RecordReliableChanges(ctx context.Context, userID int, assessment *models.Assessment) error {
+	return repository.WithUserLock(ctx, userID, func(ctx context.Context) error {
+		for _, question := range assessment.Questions {
+			test, ok := cognitive.Get(question.Type)
+			if !ok {
+				continue
+			}
+			for _, metric := range test.Metrics() {
+				if _, ok := config.Conf.Results.ReliableChange.Lookup(test.Type(), metric.Value); !ok {
+					continue
+				}
+				baseline, _, err := resolveBaseline(ctx, userID, question.ID, metric.Value, nil)
+				if err != nil {
+					return err
+				}
+				if err := refreshChangeEvents(ctx, userID, question, metric.Value, baseline); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// reliableChangeIndex returns the Jacobson-Truax reliable change index of value
+// against the baseline: the change from the baseline mean divided by the standard
+// error of the difference between two measurements. It is undefined when the
+// baseline has no spread or the reliability is perfect.
+func reliableChangeIndex(baseline *models.Baseline, reliability, value float64) (float64, bool) {
+	if !baseline.HasSpread() || reliability >= 1 {
+		return 0, false
+	}
+	standardError := baseline.SD * math.Sqrt(1-reliability)
+	return (value - baseline.Mean) / (math.Sqrt2 * standardError), true
+}
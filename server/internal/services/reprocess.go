@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+
+	"crapp-go/internal/cognitive"
+	"crapp-go/internal/models"
+	"crapp-go/internal/repository"
+	"crapp-go/internal/validity"
+
+	"go.uber.org/zap"
+)
+
+// Reprocessor re-runs the current scoring functions over stored raw payloads.
+// Every recomputed run is written as a new result that links back to the one it
+// replaces, so historical results are never modified or deleted. The validity
+// rules are applied again to the new result, including the interruptions recorded
+// for the test's question in the interaction data.
+type Reprocessor struct {
+	log        *zap.Logger
+	Assessment *models.Assessment
+}
+
+func NewReprocessor(log *zap.Logger, assessment *models.Assessment) *Reprocessor {
+	return &Reprocessor{log: log, Assessment: assessment}
+}
+
+// ReprocessSummary counts the outcome of a reprocessing run for one test.
+type ReprocessSummary struct {
+	TestType  string
+	Processed int
+	Skipped   int // Results already scored by the current algorithm version
+	Failed    int
+}
+
+// Run reprocesses the latest result of every assessment for each test, skipping
+// results already scored by the test's current algorithm version, so running it
+// again writes nothing new. With dryRun set, payloads are scored but nothing is
+// written.
+func (r *Reprocessor) Run(ctx context.Context, tests []cognitive.CognitiveTest, dryRun bool) ([]ReprocessSummary, error) {
+	summaries := make([]ReprocessSummary, 0, len(tests))
+	for _, test := range tests {
+		results, err := repository.GetLatestRawResults(ctx, test)
+		if err != nil {
+			return summaries, err
+		}
+
+		summary := ReprocessSummary{TestType: test.Type()}
+		for _, result := range results {
+			if result.AlgorithmVersion == test.AlgorithmVersion() {
+				summary.Skipped++
+				continue
+			}
+			submission, err := cognitive.Process(test, result.AssessmentID, result.RawData)
+			if err != nil {
+				r.log.Warn("Failed to reprocess result", zap.Error(err), zap.String("testType", test.Type()), zap.Uint("resultID", result.ID))
+				summary.Failed++
+				continue
+			}
+			if err := r.applyInterruptions(ctx, test, result.AssessmentID, submission); err != nil {
+				r.log.Warn("Failed to check interruptions of reprocessed result", zap.Error(err), zap.String("testType", test.Type()), zap.Uint("resultID", result.ID))
+				summary.Failed++
+				continue
+			}
+			submission.MarkReprocessed(result.ID, result.CreatedAt)
+
+			if !dryRun {
+				if err := repository.SaveCognitiveSubmission(submission); err != nil {
+					r.log.Error("Failed to save reprocessed result", zap.Error(err), zap.String("testType", test.Type()), zap.Uint("resultID", result.ID))
+					summary.Failed++
+					continue
+				}
+			}
+			summary.Processed++
+		}
+
+		r.log.Info("Reprocessed cognitive test results",
+			zap.String("testType", summary.TestType),
+			zap.Int("processed", summary.Processed),
+			zap.Int("skipped", summary.Skipped),
+			zap.Int("failed", summary.Failed),
+			zap.Bool("dryRun", dryRun))
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// applyInterruptions re-evaluates a reprocessed run's validity with the
+// interruptions recorded for its question, so a run invalidated when the
+// interaction data was finalized stays invalid.
+func (r *Reprocessor) applyInterruptions(ctx context.Context, test cognitive.CognitiveTest, assessmentID uint, submission cognitive.Submission) error {
+	for _, question := range r.Assessment.Questions {
+		if question.Type != test.Type() {
+			continue
+		}
+		recorded, err := repository.GetQuestionMetrics(ctx, assessmentID, question.ID)
+		if err != nil {
+			return err
+		}
+		facts := submission.Facts()
+		mergeInterruptionFacts(facts, recorded)
+		submission.SetValidity(validity.Evaluate(test.Type(), facts))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"crapp-go/internal/cognitive"
+	"crapp-go/internal/config"
+	"crapp-go/internal/models"
+	"crapp-go/internal/repository"
+	"crapp-go/internal/stats"
+)
+
+// Trend line methods.
+const (
+	TrendLinear = "linear"
+	TrendLoess  = "loess"
+	TrendNone   = "none"
+)
+
+// TrendPoint is one point of a derived series.
+type TrendPoint struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+}
+
+// Trend summarises how a metric moves over time. The slope always comes from the
+// least-squares line, whichever method draws the trend line, so it reads the same
+// everywhere it is reported.
+type Trend struct {
+	Method       string       `json:"method"`
+	Window       int          `json:"window"`
+	RollingMean  []TrendPoint `json:"rollingMean"`
+	Line         []TrendPoint `json:"line"`
+	HasSlope     bool         `json:"hasSlope"`
+	SlopePerWeek float64      `json:"slopePerWeek"`
+	CILow        float64      `json:"ciLow"`
+	CIHigh       float64      `json:"ciHigh"`
+	Confidence   float64      `json:"confidence"`
+	N            int          `json:"n"`
+	Summary      string       `json:"summary"`
+}
+
+// TrendSettings returns the trend method and rolling window to use, falling back
+// to the configured defaults for missing or unknown values.
+func TrendSettings(method string, window int) (string, int) {
+	switch method {
+	case TrendLinear, TrendLoess, TrendNone:
+	default:
+		method = config.Conf.Results.Trend.Method
+	}
+	if window < 1 {
+		window = config.Conf.Results.Trend.RollingWindow
+	}
+	return method, window
+}
+
+// TimelineTrend computes the rolling mean, trend line and weekly slope of a
+// timeline series ordered by date. question and metricKey tell whether a rising
+// value is an improvement; the summary only says so when that is known.
+func TimelineTrend(points []repository.TimelineDataPoint, question models.Question, metricKey, method string, window int) *Trend {
+	settings := config.Conf.Results.Trend
+	trend := &Trend{Method: method, Window: window, Confidence: settings.Confidence, N: len(points)}
+
+	x := make([]float64, len(points))
+	y := make([]float64, len(points))
+	for i, point := range points {
+		x[i] = point.Date.Sub(points[0].Date).Hours() / 24
+		y[i] = point.Value
+	}
+
+	for i, mean := range stats.RollingMean(y, window) {
+		if !math.IsNaN(mean) {
+			trend.RollingMean = append(trend.RollingMean, TrendPoint{Date: points[i].Date, Value: mean})
+		}
+	}
+
+	fit, err := stats.LinearFit(x, y)
+	if err != nil {
+		trend.Summary = "Not enough assessments for a trend yet."
+		return trend
+	}
+
+	switch method {
+	case TrendLinear:
+		for i, point := range points {
+			trend.Line = append(trend.Line, TrendPoint{Date: point.Date, Value: fit.Predict(x[i])})
+		}
+	case TrendLoess:
+		if smoothed, err := stats.Loess(x, y, settings.LoessSpan); err == nil {
+			for i, point := range points {
+				trend.Line = append(trend.Line, TrendPoint{Date: point.Date, Value: smoothed[i]})
+			}
+		}
+	}
+
+	if fit.N < 3 {
+		trend.Summary = "Not enough assessments for a trend yet."
+		return trend
+	}
+	low, high := fit.SlopeCI(settings.Confidence)
+	trend.HasSlope = true
+	trend.SlopePerWeek, trend.CILow, trend.CIHigh = fit.Slope*7, low*7, high*7
+	trend.Summary = fmt.Sprintf("%s %.2f points/week (%.0f%% CI %.2f to %.2f, n = %d)",
+		trendDirection(trend, question, metricKey), trend.SlopePerWeek, settings.Confidence*100, trend.CILow, trend.CIHigh, trend.N)
+	return trend
+}
+
+// trendDirection describes the slope in words. A slope whose interval includes
+// zero is not distinguishable from a flat line.
+func trendDirection(trend *Trend, question models.Question, metricKey string) string {
+	if trend.CILow <= 0 && trend.CIHigh >= 0 {
+		return "No clear trend:"
+	}
+	rising := trend.SlopePerWeek > 0
+	if test, ok := cognitive.Get(question.Type); ok {
+		if rule, ok := config.Conf.Results.ReliableChange.Lookup(test.Type(), metricKey); ok {
+			if rising != rule.LowerIsBetter {
+				return "Improving"
+			}
+			return "Declining"
+		}
+	}
+	if rising {
+		return "Rising"
+	}
+	return "Falling"
+}
@@ -0,0 +1,89 @@
+// server/internal/stats/correlation.go
+package stats
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrConstant is returned when a correlation involves a variable with no spread.
+var ErrConstant = errors.New("stats: variable is constant")
+
+// Correlation is a correlation coefficient with its two-sided p-value for the
+// null hypothesis of no correlation.
+type Correlation struct {
+	R float64
+	P float64
+	N int
+}
+
+// Pearson returns the Pearson product-moment correlation of x and y. The p-value
+// comes from the t distribution with N-2 degrees of freedom, so at least three
+// pairs are needed and neither variable may be constant.
+func Pearson(x, y []float64) (Correlation, error) {
+	n := len(x)
+	if n != len(y) {
+		return Correlation{}, errors.New("stats: x and y differ in length")
+	}
+	if n < 3 {
+		return Correlation{}, ErrTooFewPoints
+	}
+	meanX, meanY := Mean(x), Mean(y)
+	var sxx, syy, sxy float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		sxx += dx * dx
+		syy += dy * dy
+		sxy += dx * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return Correlation{}, ErrConstant
+	}
+
+	r := math.Max(-1, math.Min(1, sxy/math.Sqrt(sxx*syy)))
+	return Correlation{R: r, P: correlationP(r, n), N: n}, nil
+}
+
+// Spearman returns Spearman's rank correlation of x and y: the Pearson
+// correlation of their ranks, with tied values sharing their mean rank. The
+// p-value uses the same t approximation as Pearson.
+func Spearman(x, y []float64) (Correlation, error) {
+	if len(x) != len(y) {
+		return Correlation{}, errors.New("stats: x and y differ in length")
+	}
+	return Pearson(Ranks(x), Ranks(y))
+}
+
+// Ranks returns the 1-based rank of each value, averaging the ranks of ties.
+func Ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+
+	ranks := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+		rank := float64(start+end+1) / 2 // Mean of the ranks start+1 … end
+		for _, i := range order[start:end] {
+			ranks[i] = rank
+		}
+		start = end
+	}
+	return ranks
+}
+
+// correlationP returns the two-sided p-value of a correlation r over n pairs.
+func correlationP(r float64, n int) float64 {
+	if math.Abs(r) == 1 {
+		return 0
+	}
+	df := float64(n - 2)
+	t := r * math.Sqrt(df/(1-r*r))
+	return 2 * (1 - StudentTCDF(math.Abs(t), df))
+}
@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+)
+
+// Anscombe's first data set, whose correlation and regression p-value are widely
+// tabulated.
+var (
+	anscombeX = []float64{10, 8, 13, 9, 11, 14, 6, 4, 12, 7, 5}
+	anscombeY = []float64{8.04, 6.95, 7.58, 8.81, 8.33, 9.96, 7.24, 4.26, 10.84, 4.82, 5.68}
+)
+
+func TestPearson(t *testing.T) {
+	tests := []struct {
+		name    string
+		x, y    []float64
+		wantR   float64
+		wantP   float64
+		wantN   int
+		wantErr error
+	}{
+		{name: "anscombe", x: anscombeX, y: anscombeY, wantR: 0.81642051634484, wantP: 0.0021696288730785884, wantN: 11},
+		{name: "perfect positive", x: []float64{1, 2, 3, 4}, y: []float64{3, 5, 7, 9}, wantR: 1, wantP: 0, wantN: 4},
+		{name: "perfect negative", x: []float64{1, 2, 3}, y: []float64{3, 2, 1}, wantR: -1, wantP: 0, wantN: 3},
+		{name: "two points", x: []float64{1, 2}, y: []float64{2, 1}, wantErr: ErrTooFewPoints},
+		{name: "empty", x: nil, y: nil, wantErr: ErrTooFewPoints},
+		{name: "constant", x: []float64{1, 2, 3}, y: []float64{4, 4, 4}, wantErr: ErrConstant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Pearson(tt.x, tt.y)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !near(got.R, tt.wantR, 1e-12) || !near(got.P, tt.wantP, 1e-9) || got.N != tt.wantN {
+				t.Errorf("Pearson = %+v, want R %v, P %v, N %d", got, tt.wantR, tt.wantP, tt.wantN)
+			}
+		})
+	}
+
+	if _, err := Pearson([]float64{1, 2, 3}, []float64{1, 2}); err == nil {
+		t.Error("Pearson of unequal lengths: want an error")
+	}
+}
+
+func TestSpearman(t *testing.T) {
+	tests := []struct {
+		name    string
+		x, y    []float64
+		wantR   float64
+		wantP   float64
+		wantErr error
+	}{
+		{
+			// IQ against hours of television per week, the worked example on
+			// Wikipedia: ρ = -29/165, p = 0.627188.
+			name:  "no ties",
+			x:     []float64{106, 100, 86, 101, 99, 103, 97, 113, 112, 110},
+			y:     []float64{7, 27, 2, 50, 28, 29, 20, 12, 6, 17},
+			wantR: -29.0 / 165,
+			wantP: 0.6271883447764843,
+		},
+		{
+			// The two 7s share rank 3.5.
+			name:  "ties",
+			x:     []float64{1, 2, 3, 4, 5},
+			y:     []float64{5, 6, 7, 8, 7},
+			wantR: 0.8207826816681233,
+			wantP: 0.0885870053135438,
+		},
+		{
+			// Monotonic but not linear
+			name:  "monotonic",
+			x:     []float64{1, 2, 3, 4, 5},
+			y:     []float64{1, 4, 9, 16, 1000},
+			wantR: 1,
+			wantP: 0,
+		},
+		{name: "two points", x: []float64{1, 2}, y: []float64{1, 2}, wantErr: ErrTooFewPoints},
+		{name: "all tied", x: []float64{1, 2, 3}, y: []float64{5, 5, 5}, wantErr: ErrConstant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Spearman(tt.x, tt.y)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !near(got.R, tt.wantR, 1e-12) || !near(got.P, tt.wantP, 1e-9) {
+				t.Errorf("Spearman = %+v, want R %v, P %v", got, tt.wantR, tt.wantP)
+			}
+		})
+	}
+}
+
+func TestRanks(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   []float64
+	}{
+		{[]float64{30, 10, 20}, []float64{3, 1, 2}},
+		{[]float64{10, 20, 20, 30}, []float64{1, 2.5, 2.5, 4}},
+		{[]float64{5, 5, 5}, []float64{2, 2, 2}},
+		{[]float64{}, []float64{}},
+	}
+	for _, tt := range tests {
+		got := Ranks(tt.values)
+		if len(got) != len(tt.want) {
+			t.Fatalf("Ranks(%v) = %v, want %v", tt.values, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Ranks(%v) = %v, want %v", tt.values, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+// near reports whether got is within tol of want.
+func near(got, want, tol float64) bool {
+	return math.Abs(got-want) <= tol
+}
+
+func TestStudentTCDF(t *testing.T) {
+	// With one and two degrees of freedom the CDF has a closed form:
+	// 1/2 + atan(t)/π and 1/2 + t/(2√(2+t²)).
+	tests := []struct {
+		t, df, want float64
+	}{
+		{0, 1, 0.5},
+		{0, 30, 0.5},
+		{1, 1, 0.75},
+		{-2, 1, 0.14758361765043326},
+		{3, 1, 0.8975836176504333},
+		{0.5, 2, 0.6666666666666666},
+		{-2, 2, 0.09175170953613693},
+		{3, 2, 0.9522670168666454},
+		// Tabulated two-sided 5% critical values
+		{2.570581836, 5, 0.975},
+		{2.228138852, 10, 0.975},
+		{1.812461123, 10, 0.95},
+		{1.979930405, 120, 0.975},
+	}
+	for _, tt := range tests {
+		if got := StudentTCDF(tt.t, tt.df); !near(got, tt.want, 1e-9) {
+			t.Errorf("StudentTCDF(%v, %v) = %v, want %v", tt.t, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestStudentTQuantile(t *testing.T) {
+	tests := []struct {
+		p, df, want float64
+	}{
+		{0.5, 4, 0},
+		{0.975, 1, 12.706204736},
+		{0.975, 2, 4.302652730},
+		{0.975, 3, 3.182446305},
+		{0.975, 5, 2.570581836},
+		{0.975, 10, 2.228138852},
+		{0.975, 30, 2.042272456},
+		{0.95, 10, 1.812461123},
+		{0.995, 20, 2.845339707},
+		{0.025, 10, -2.228138852},
+	}
+	for _, tt := range tests {
+		if got := StudentTQuantile(tt.p, tt.df); !near(got, tt.want, 1e-8) {
+			t.Errorf("StudentTQuantile(%v, %v) = %v, want %v", tt.p, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestRegularizedIncompleteBeta(t *testing.T) {
+	tests := []struct {
+		x, a, b, want float64
+	}{
+		{0, 2, 3, 0},
+		{1, 2, 3, 1},
+		{0.3, 1, 1, 0.3},                     // Uniform: I_x(1, 1) = x
+		{0.4, 3, 1, 0.064},                   // I_x(a, 1) = x^a
+		{0.4, 1, 3, 0.784},                   // I_x(1, b) = 1 - (1-x)^b
+		{0.5, 4.5, 4.5, 0.5},                 // Symmetric about one half when a = b
+		{0.3, 2, 3, 0.3483},                  // Binomial tail: P(Bin(4, 0.3) >= 2)
+		{0.9, 2, 3, 0.9963},                  // Same, above the continued fraction's switch point
+		{0.2, 5, 10, 0.12983962583040012},    // P(Bin(14, 0.2) >= 5)
+		{0.75, 0.5, 0.5, 0.6666666666666667}, // Arcsine: 2/π asin(√x)
+		{0.1, 0.5, 0.5, 0.20483276469913345}, // Arcsine: 2/π asin(√x)
+	}
+	for _, tt := range tests {
+		if got := regularizedIncompleteBeta(tt.x, tt.a, tt.b); !near(got, tt.want, 1e-10) {
+			t.Errorf("regularizedIncompleteBeta(%v, %v, %v) = %v, want %v", tt.x, tt.a, tt.b, got, tt.want)
+		}
+	}
+}
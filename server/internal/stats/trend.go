@@ -0,0 +1,173 @@
+// server/internal/stats/trend.go
+package stats
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrTooFewPoints is returned when a statistic needs more points than it was given.
+var ErrTooFewPoints = errors.New("stats: too few points")
+
+// Mean returns the arithmetic mean of values, or NaN if there are none.
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// StdDev returns the sample standard deviation of values, or NaN if there are
+// fewer than two.
+func StdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return math.NaN()
+	}
+	mean := Mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// RollingMean returns the trailing mean of each value and the window-1 values
+// before it. The first window-1 results are NaN, as their window is incomplete.
+func RollingMean(values []float64, window int) []float64 {
+	means := make([]float64, len(values))
+	if window < 1 {
+		window = 1
+	}
+	var sum float64
+	for i, v := range values {
+		sum += v
+		if i >= window {
+			sum -= values[i-window]
+		}
+		if i < window-1 {
+			means[i] = math.NaN()
+		} else {
+			means[i] = sum / float64(window)
+		}
+	}
+	return means
+}
+
+// Linear is a least-squares line y = Intercept + Slope*x.
+type Linear struct {
+	Slope     float64
+	Intercept float64
+	SlopeSE   float64 // Standard error of the slope
+	N         int
+}
+
+// Predict returns the fitted value at x.
+func (l Linear) Predict(x float64) float64 {
+	return l.Intercept + l.Slope*x
+}
+
+// SlopeCI returns the two-sided confidence interval of the slope at the given
+// level (e.g. 0.95), from the t distribution with N-2 degrees of freedom.
+func (l Linear) SlopeCI(level float64) (low, high float64) {
+	if l.N < 3 {
+		return math.Inf(-1), math.Inf(1)
+	}
+	margin := StudentTQuantile(1-(1-level)/2, float64(l.N-2)) * l.SlopeSE
+	return l.Slope - margin, l.Slope + margin
+}
+
+// LinearFit fits a least-squares line through the points (x[i], y[i]). It needs
+// at least two distinct x values.
+func LinearFit(x, y []float64) (Linear, error) {
+	n := len(x)
+	if n != len(y) {
+		return Linear{}, errors.New("stats: x and y differ in length")
+	}
+	if n < 2 {
+		return Linear{}, ErrTooFewPoints
+	}
+	meanX, meanY := Mean(x), Mean(y)
+	var sxx, sxy float64
+	for i := range x {
+		sxx += (x[i] - meanX) * (x[i] - meanX)
+		sxy += (x[i] - meanX) * (y[i] - meanY)
+	}
+	if sxx == 0 {
+		return Linear{}, ErrTooFewPoints
+	}
+
+	fit := Linear{Slope: sxy / sxx, N: n}
+	fit.Intercept = meanY - fit.Slope*meanX
+	if n > 2 {
+		var residuals float64
+		for i := range x {
+			r := y[i] - fit.Predict(x[i])
+			residuals += r * r
+		}
+		fit.SlopeSE = math.Sqrt(residuals / float64(n-2) / sxx)
+	}
+	return fit, nil
+}
+
+// Loess returns the locally weighted linear regression of y on x, evaluated at
+// each x. Every local fit uses the nearest span*n points (at least three),
+// weighted by the tricube of their distance. x must be sorted in ascending order.
+func Loess(x, y []float64, span float64) ([]float64, error) {
+	n := len(x)
+	if n != len(y) {
+		return nil, errors.New("stats: x and y differ in length")
+	}
+	if n < 3 {
+		return nil, ErrTooFewPoints
+	}
+	q := int(math.Ceil(span * float64(n)))
+	q = max(3, min(q, n))
+
+	fitted := make([]float64, n)
+	distances := make([]float64, n)
+	for i, x0 := range x {
+		for j := range x {
+			distances[j] = math.Abs(x[j] - x0)
+		}
+		sorted := append([]float64(nil), distances...)
+		sort.Float64s(sorted)
+		radius := sorted[q-1]
+
+		var sw, swx, swy, swxx, swxy float64
+		for j := range x {
+			w := 1.0
+			if radius > 0 {
+				w = tricube(distances[j] / radius)
+			} else if distances[j] > 0 {
+				w = 0
+			}
+			sw += w
+			swx += w * x[j]
+			swy += w * y[j]
+			swxx += w * x[j] * x[j]
+			swxy += w * x[j] * y[j]
+		}
+		// Fall back to the weighted mean where the neighbourhood has no spread in x.
+		denominator := sw*swxx - swx*swx
+		if math.Abs(denominator) < 1e-12*sw*swxx || sw == 0 {
+			fitted[i] = swy / sw
+			continue
+		}
+		slope := (sw*swxy - swx*swy) / denominator
+		fitted[i] = (swy-slope*swx)/sw + slope*x0
+	}
+	return fitted, nil
+}
+
+func tricube(u float64) float64 {
+	if u >= 1 {
+		return 0
+	}
+	v := 1 - u*u*u
+	return v * v * v
+}
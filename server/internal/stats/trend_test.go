@@ -0,0 +1,161 @@
+package stats
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestStdDev(t *testing.T) {
+	// Squared deviations from the mean of 5 sum to 32, over n-1 = 7.
+	if got, want := StdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9}), math.Sqrt(32.0/7); !near(got, want, 1e-12) {
+		t.Errorf("StdDev = %v, want %v", got, want)
+	}
+	for _, values := range [][]float64{nil, {3}} {
+		if got := StdDev(values); !math.IsNaN(got) {
+			t.Errorf("StdDev(%v) = %v, want NaN", values, got)
+		}
+	}
+}
+
+func TestRollingMean(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name   string
+		values []float64
+		window int
+		want   []float64
+	}{
+		{"window of three", []float64{1, 2, 3, 4, 5}, 3, []float64{nan, nan, 2, 3, 4}},
+		{"window of one", []float64{4, 8, 15}, 1, []float64{4, 8, 15}},
+		{"window below one", []float64{4, 8, 15}, 0, []float64{4, 8, 15}},
+		{"window longer than values", []float64{1, 2}, 5, []float64{nan, nan}},
+		{"window equal to values", []float64{2, 4, 9}, 3, []float64{nan, nan, 5}},
+		{"empty", nil, 3, []float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RollingMean(tt.values, tt.window)
+			if len(got) != len(tt.want) {
+				t.Fatalf("RollingMean = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.IsNaN(tt.want[i]) != math.IsNaN(got[i]) || (!math.IsNaN(got[i]) && !near(got[i], tt.want[i], 1e-12)) {
+					t.Errorf("RollingMean = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestLinearFit(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5}
+	y := []float64{2, 4, 5, 4, 5}
+	fit, err := LinearFit(x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Slope 0.6, intercept 2.2, residual sum of squares 2.4 over Sxx = 10, so the
+	// standard error is √(2.4/3/10).
+	if !near(fit.Slope, 0.6, 1e-12) || !near(fit.Intercept, 2.2, 1e-12) || !near(fit.SlopeSE, math.Sqrt(0.08), 1e-12) || fit.N != 5 {
+		t.Errorf("LinearFit = %+v", fit)
+	}
+
+	for _, tt := range []struct {
+		name string
+		x, y []float64
+	}{
+		{"one point", []float64{1}, []float64{1}},
+		{"no spread in x", []float64{2, 2, 2}, []float64{1, 2, 3}},
+	} {
+		if _, err := LinearFit(tt.x, tt.y); !errors.Is(err, ErrTooFewPoints) {
+			t.Errorf("LinearFit %s: err = %v, want %v", tt.name, err, ErrTooFewPoints)
+		}
+	}
+}
+
+func TestSlopeCI(t *testing.T) {
+	tests := []struct {
+		name            string
+		fit             Linear
+		level           float64
+		wantLow, wantHi float64
+	}{
+		{
+			// The fit of TestLinearFit: 0.6 ± t(0.975, 3)·√0.08, with t = 3.182446305.
+			name:    "95%",
+			fit:     Linear{Slope: 0.6, SlopeSE: math.Sqrt(0.08), N: 5},
+			level:   0.95,
+			wantLow: -0.3001317452914304,
+			wantHi:  1.5001317452914305,
+		},
+		{
+			// 2 ± t(0.95, 10)·0.5, with t = 1.812461123.
+			name:    "90%",
+			fit:     Linear{Slope: 2, SlopeSE: 0.5, N: 12},
+			level:   0.90,
+			wantLow: 2 - 1.812461123*0.5,
+			wantHi:  2 + 1.812461123*0.5,
+		},
+		{
+			name:    "two points",
+			fit:     Linear{Slope: 1, N: 2},
+			level:   0.95,
+			wantLow: math.Inf(-1),
+			wantHi:  math.Inf(1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			low, high := tt.fit.SlopeCI(tt.level)
+			if math.IsInf(tt.wantLow, -1) {
+				if !math.IsInf(low, -1) || !math.IsInf(high, 1) {
+					t.Errorf("SlopeCI = (%v, %v), want unbounded", low, high)
+				}
+				return
+			}
+			if !near(low, tt.wantLow, 1e-8) || !near(high, tt.wantHi, 1e-8) {
+				t.Errorf("SlopeCI = (%v, %v), want (%v, %v)", low, high, tt.wantLow, tt.wantHi)
+			}
+		})
+	}
+}
+
+func TestLoess(t *testing.T) {
+	// At x = 3 with span 1 the neighbourhood is all five points, with tricube
+	// weights 0, (7/8)³, 1, (7/8)³, 0. They are symmetric, so the local line
+	// passes through their weighted mean.
+	centre := (4*math.Pow(7.0/8, 3) + 5 + 4*math.Pow(7.0/8, 3)) / (1 + 2*math.Pow(7.0/8, 3))
+
+	tests := []struct {
+		name  string
+		x, y  []float64
+		span  float64
+		index int
+		want  float64
+	}{
+		{"symmetric neighbourhood", []float64{1, 2, 3, 4, 5}, []float64{2, 4, 5, 4, 5}, 1, 2, centre},
+		{"straight line is reproduced", []float64{0, 1, 2, 4, 7, 8}, []float64{1, 3, 5, 9, 15, 17}, 0.5, 4, 15},
+		{"constant is reproduced", []float64{1, 2, 3, 4}, []float64{6, 6, 6, 6}, 0.75, 1, 6},
+		{"duplicate x falls back to the mean", []float64{1, 1, 1}, []float64{2, 4, 9}, 1, 0, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fitted, err := Loess(tt.x, tt.y, tt.span)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fitted) != len(tt.x) {
+				t.Fatalf("Loess returned %d values for %d points", len(fitted), len(tt.x))
+			}
+			if !near(fitted[tt.index], tt.want, 1e-9) {
+				t.Errorf("Loess at x = %v is %v, want %v", tt.x[tt.index], fitted[tt.index], tt.want)
+			}
+		})
+	}
+
+	if _, err := Loess([]float64{1, 2}, []float64{1, 2}, 1); !errors.Is(err, ErrTooFewPoints) {
+		t.Errorf("Loess of two points: err = %v, want %v", err, ErrTooFewPoints)
+	}
+}
@@ -0,0 +1,32 @@
+// server/internal/validity/validity.go
+package validity
+
+import (
+	"crapp-go/internal/config"
+	"crapp-go/internal/models"
+)
+
+// Evaluate applies the configured validity rules for a test type to the facts
+// reported by a single run. Rules that reference a fact the run did not report
+// are skipped. A run is invalid if any rule fails.
+func Evaluate(testType string, facts map[string]float64) models.Validity {
+	result := models.Validity{ValidityStatus: models.ValidityValid}
+	if config.Conf == nil {
+		return result
+	}
+
+	for _, rule := range config.Conf.Validity.Rules {
+		if rule.Test != "*" && rule.Test != testType {
+			continue
+		}
+		value, ok := facts[rule.Fact]
+		if !ok {
+			continue
+		}
+		if (rule.Min != nil && value < *rule.Min) || (rule.Max != nil && value > *rule.Max) {
+			result.ValidityStatus = models.ValidityInvalid
+			result.ValidityReasons = append(result.ValidityReasons, rule.Reason)
+		}
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package validity
+
+import (
+	"slices"
+	"testing"
+
+	"crapp-go/internal/config"
+	"crapp-go/internal/models"
+)
+
+func TestEvaluate(t *testing.T) {
+	bound := func(v float64) *float64 { return &v }
+	previous := config.Conf
+	t.Cleanup(func() { config.Conf = previous })
+	config.Conf = &config.Config{Validity: config.ValidityConfig{Rules: []config.ValidityRule{
+		{Test: "cpt", Fact: "response_count", Min: bound(1), Reason: "no responses"},
+		{Test: "tmt", Fact: "timed_out", Max: bound(0), Reason: "timed out"},
+		{Test: "*", Fact: "interruption_count", Max: bound(0), Reason: "interrupted"},
+		{Test: "*", Fact: "reaction_time", Min: bound(100), Max: bound(2000), Reason: "implausible reaction time"},
+	}}}
+
+	tests := []struct {
+		name     string
+		testType string
+		facts    map[string]float64
+		want     []string
+	}{
+		{"all rules pass", "cpt", map[string]float64{"response_count": 12, "interruption_count": 0, "reaction_time": 450}, nil},
+		{"below a minimum", "cpt", map[string]float64{"response_count": 0}, []string{"no responses"}},
+		{"minimum is inclusive", "cpt", map[string]float64{"response_count": 1}, nil},
+		{"above a maximum", "tmt", map[string]float64{"timed_out": 1}, []string{"timed out"}},
+		{"rule for another test is skipped", "dst", map[string]float64{"response_count": 0, "timed_out": 1}, nil},
+		{"wildcard applies to every test", "dst", map[string]float64{"interruption_count": 2}, []string{"interrupted"}},
+		{"missing fact is skipped", "cpt", map[string]float64{}, nil},
+		{"both bounds, too low", "cpt", map[string]float64{"reaction_time": 80}, []string{"implausible reaction time"}},
+		{"both bounds, too high", "cpt", map[string]float64{"reaction_time": 2500}, []string{"implausible reaction time"}},
+		{
+			"every failing rule gives its reason", "cpt",
+			map[string]float64{"response_count": 0, "interruption_count": 1},
+			[]string{"no responses", "interrupted"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Evaluate(tt.testType, tt.facts)
+			wantStatus := models.ValidityValid
+			if len(tt.want) > 0 {
+				wantStatus = models.ValidityInvalid
+			}
+			if got.ValidityStatus != wantStatus || !slices.Equal([]string(got.ValidityReasons), tt.want) {
+				t.Errorf("Evaluate = %s %v, want %s %v", got.ValidityStatus, got.ValidityReasons, wantStatus, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithoutConfig(t *testing.T) {
+	previous := config.Conf
+	t.Cleanup(func() { config.Conf = previous })
+	config.Conf = nil
+
+	if got := Evaluate("cpt", map[string]float64{"response_count": 0}); got.ValidityStatus != models.ValidityValid {
+		t.Errorf("Evaluate without configuration = %+v, want valid", got)
+	}
+}
@@ -46,6 +46,20 @@ func main() {
 		log.Fatal("Failed to load assessment", zap.Error(err))
 	}
 
+	// Subcommands run against the database and exit instead of starting the server.
+	if len(os.Args) > 1 && os.Args[1] == "reprocess" {
+		if err := runReprocess(log, assessment, os.Args[2:]); err != nil {
+			log.Fatal("Reprocessing failed", zap.Error(err))
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "import-norms" {
+		if err := runImportNorms(log, os.Args[2:]); err != nil {
+			log.Fatal("Importing normative data failed", zap.Error(err))
+		}
+		return
+	}
+
 	// Initialize Services
 	emailService := services.NewEmailService(log)
 	scheduler := services.NewScheduler(log, emailService)
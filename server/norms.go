@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"crapp-go/internal/norms"
+	"crapp-go/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// runImportNorms implements the "import-norms" subcommand:
+//
+//	crapp import-norms -file norms.csv
+//
+// It loads a normative table from a CSV or YAML file, replacing the stored groups
+// of every metric the file covers.
+func runImportNorms(log *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("import-norms", flag.ContinueOnError)
+	file := fs.String("file", "", "CSV or YAML file holding the normative table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	entries, err := norms.Load(*file)
+	if err != nil {
+		return err
+	}
+	if err := repository.ReplaceNormativeEntries(context.Background(), entries); err != nil {
+		return err
+	}
+
+	log.Info("Imported normative table", zap.String("file", *file), zap.Int("entries", len(entries)))
+	fmt.Printf("%d normative groups imported\n", len(entries))
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"crapp-go/internal/cognitive"
+	"crapp-go/internal/models"
+	"crapp-go/internal/services"
+
+	"go.uber.org/zap"
+)
+
+// runReprocess implements the "reprocess" subcommand:
+//
+//	crapp reprocess [-test cpt] [-dry-run]
+//
+// It re-scores stored raw payloads with the current algorithms.
+func runReprocess(log *zap.Logger, assessment *models.Assessment, args []string) error {
+	fs := flag.NewFlagSet("reprocess", flag.ContinueOnError)
+	testType := fs.String("test", "", "only reprocess this test type (e.g. cpt); defaults to all tests")
+	dryRun := fs.Bool("dry-run", false, "score payloads without writing new results")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	tests := cognitive.All()
+	if *testType != "" {
+		test, ok := cognitive.Get(*testType)
+		if !ok {
+			return fmt.Errorf("unknown test type %q", *testType)
+		}
+		tests = []cognitive.CognitiveTest{test}
+	}
+
+	summaries, err := services.NewReprocessor(log, assessment).Run(context.Background(), tests, *dryRun)
+	for _, s := range summaries {
+		fmt.Printf("%s: %d reprocessed, %d already current, %d failed\n", s.TestType, s.Processed, s.Skipped, s.Failed)
+	}
+	return err
+}